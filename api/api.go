@@ -1,21 +1,40 @@
 package api
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/gin-gonic/gin"
 	v1 "github.com/samandar2605/medium_api_gateway/api/v1"
 	"github.com/samandar2605/medium_api_gateway/config"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 
 	_ "github.com/samandar2605/medium_api_gateway/api/docs" // for swagger
 
+	"github.com/samandar2605/medium_api_gateway/pkg/cache"
 	grpcPkg "github.com/samandar2605/medium_api_gateway/pkg/grpc_client"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+	"github.com/samandar2605/medium_api_gateway/pkg/policy"
+	"github.com/samandar2605/medium_api_gateway/pkg/ratelimit"
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
 )
 
 type RouterOptions struct {
 	Cfg        *config.Config
 	GrpcClient grpcPkg.GrpcClientI
+	Catalog    *i18n.Catalog
+	Tokens     *token.Maker
+	Policy     *policy.Policy
+	Store      store.Store
+	// RateLimiter keeps the request counts of every route group.
+	RateLimiter ratelimit.Limiter
+	// ResponseCache keeps the responses of the cached public reads.
+	ResponseCache cache.Cache
 }
 
 // @title           Swagger for blog api
@@ -27,57 +46,145 @@ type RouterOptions struct {
 // @in header
 // @name Authorization
 
-func New(opt *RouterOptions) *gin.Engine {
-	router := gin.Default()
+func New(opt *RouterOptions) (*gin.Engine, error) {
+	router := gin.New()
+	// Client IPs are what rate limits and lockouts count against, so
+	// only the configured proxies may set them.
+	if err := router.SetTrustedProxies(opt.Cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
 
 	handlerV1 := v1.New(&v1.HandlerV1Options{
-		Cfg:        opt.Cfg,
-		GrpcClient: &opt.GrpcClient,
+		Cfg:           opt.Cfg,
+		GrpcClient:    &opt.GrpcClient,
+		Catalog:       opt.Catalog,
+		Tokens:        opt.Tokens,
+		Policy:        opt.Policy,
+		Store:         opt.Store,
+		RateLimiter:   opt.RateLimiter,
+		ResponseCache: opt.ResponseCache,
+		AuditLog:      os.Stdout,
 	})
 
+	router.Use(
+		otelgin.Middleware(opt.Cfg.ServiceName),
+		v1.RequestID(),
+		v1.AccessLog(os.Stdout),
+		v1.Metrics(),
+		gin.Recovery(),
+		handlerV1.Localize(),
+	)
+
+	router.GET("/healthz", handlerV1.Healthz)
+	router.GET("/readyz", handlerV1.Readyz)
+
 	apiV1 := router.Group("/v1")
 
 	// Category
-	apiV1.GET("/categories", handlerV1.GetCategoryAll)
-	apiV1.GET("/categories/:id", handlerV1.GetCategory)
-	apiV1.POST("/categories",handlerV1.AuthMiddleware("categories","create"), handlerV1.CreateCategory)
-	apiV1.PUT("/categories/:id",handlerV1.AuthMiddleware("categories","update"), handlerV1.UpdateCategory)
-	apiV1.DELETE("/categories/:id",handlerV1.AuthMiddleware("categories","delete"), handlerV1.DeleteCategory)
+	category := apiV1.Group("/categories",
+		v1.Deadline(opt.Cfg.CategoryTimeout),
+		handlerV1.RateLimit("categories", opt.Cfg.CategoryRateLimit),
+	)
+	category.GET("", handlerV1.CacheResponse(v1.CacheNamespaceCategories, opt.Cfg.CategoryCacheTTL), v1.Coalesce(), handlerV1.GetCategoryAll)
+	category.GET("/:id", v1.ETag(), handlerV1.CacheResponse(v1.CacheNamespaceCategories, opt.Cfg.CategoryCacheTTL), v1.Coalesce(), handlerV1.GetCategory)
+	category.POST("", handlerV1.AuthMiddleware("categories", "create"), handlerV1.CreateCategory)
+	category.PUT("/:id", handlerV1.AuthMiddleware("categories", "update"), handlerV1.UpdateCategory)
+	category.DELETE("/:id", handlerV1.AuthMiddleware("categories", "delete"), handlerV1.DeleteCategory)
 
 	// Like
-	apiV1.POST("/likes",handlerV1.AuthMiddleware("likes","create"), handlerV1.CreateOrUpdateLike)
-	apiV1.GET("/likes/user-post",handlerV1.AuthMiddleware("likes","get"), handlerV1.GetLike)
-	
+	like := apiV1.Group("/likes",
+		v1.Deadline(opt.Cfg.LikeTimeout),
+		handlerV1.RateLimit("likes", opt.Cfg.LikeRateLimit),
+	)
+	like.POST("", handlerV1.AuthMiddleware("likes", "create"), handlerV1.CreateOrUpdateLike)
+	like.GET("/user-post", handlerV1.AuthMiddleware("likes", "get"), handlerV1.GetLike)
+
 	// User
-	apiV1.GET("/users", handlerV1.GetAllUsers)
-	apiV1.GET("/users/:id", handlerV1.GetUser)
-	apiV1.POST("/users",handlerV1.AuthMiddleware("users","create"), handlerV1.CreateUser)
-	apiV1.PUT("/users/:id",handlerV1.AuthMiddleware("users","update"), handlerV1.UpdateUser)
-	apiV1.DELETE("/users/:id",handlerV1.AuthMiddleware("users","delete"), handlerV1.DeleteUser)
+	user := apiV1.Group("/users",
+		v1.Deadline(opt.Cfg.UserTimeout),
+		handlerV1.RateLimit("users", opt.Cfg.UserRateLimit),
+	)
+	user.GET("", handlerV1.GetAllUsers)
+	user.GET("/:id", v1.ETag(), v1.Coalesce(), handlerV1.GetUser)
+	user.POST("", handlerV1.AuthMiddleware("users", "create"), handlerV1.CreateUser)
+	user.PUT("/:id", handlerV1.AuthMiddleware("users", "update"), handlerV1.UpdateUser)
+	user.DELETE("/:id", handlerV1.AuthMiddleware("users", "delete"), handlerV1.DeleteUser)
+	// PUT /:id/role, for superadmins to promote and demote users, waits
+	// for user_service's UpdateUser rpc to carry the user type.
 
 	// Comment
-	apiV1.GET("/comments", handlerV1.GetAllComment)
-	apiV1.GET("/comments/:id", handlerV1.GetComment)
-	apiV1.POST("/comments",handlerV1.AuthMiddleware("comments","create"), handlerV1.CreateComment)
-	apiV1.PUT("/comments/:id",handlerV1.AuthMiddleware("comments","update"),  handlerV1.UpdateComment)
-	apiV1.DELETE("/comments/:id",handlerV1.AuthMiddleware("comments","delete"),  handlerV1.DeleteComment)
+	comment := apiV1.Group("/comments",
+		v1.Deadline(opt.Cfg.CommentTimeout),
+		handlerV1.RateLimit("comments", opt.Cfg.CommentRateLimit),
+	)
+	comment.GET("", v1.Coalesce(), handlerV1.GetAllComment)
+	comment.GET("/:id", v1.ETag(), v1.Coalesce(), handlerV1.GetComment)
+	comment.POST("", handlerV1.AuthMiddleware("comments", "create"), handlerV1.CreateComment)
+	comment.PUT("/:id", handlerV1.AuthMiddleware("comments", "update"), handlerV1.UpdateComment)
+	comment.DELETE("/:id", handlerV1.AuthMiddleware("comments", "delete"), handlerV1.DeleteComment)
 
 	// Post
-	apiV1.GET("/posts", handlerV1.GetAllPost)
-	apiV1.GET("/posts/:id", handlerV1.GetPost)
-	apiV1.POST("/posts",handlerV1.AuthMiddleware("posts","create"),  handlerV1.CreatePost)
-	apiV1.PUT("/posts/:id",handlerV1.AuthMiddleware("posts","update"),  handlerV1.UpdatePost)
-	apiV1.DELETE("/posts/:id",handlerV1.AuthMiddleware("posts","delete"),  handlerV1.DeletePost)
+	post := apiV1.Group("/posts",
+		v1.Deadline(opt.Cfg.PostTimeout),
+		handlerV1.RateLimit("posts", opt.Cfg.PostRateLimit),
+	)
+	post.GET("", handlerV1.CacheResponse(v1.CacheNamespacePosts, opt.Cfg.PostListCacheTTL), v1.Coalesce(), handlerV1.GetAllPost)
+	post.GET("/:id", v1.ETag(), handlerV1.CacheResponse(v1.CacheNamespacePosts, opt.Cfg.PostCacheTTL), v1.Coalesce(), handlerV1.GetPost)
+	post.GET("/:id/full", v1.ETag(), v1.Coalesce(), handlerV1.GetPostFull)
+	post.POST("", handlerV1.AuthMiddleware("posts", "create"), handlerV1.CreatePost)
+	post.PUT("/:id", handlerV1.AuthMiddleware("posts", "update"), handlerV1.UpdatePost)
+	post.DELETE("/:id", handlerV1.AuthMiddleware("posts", "delete"), handlerV1.DeletePost)
 
 	// Register
-	apiV1.POST("/auth/register", handlerV1.Register)
-	apiV1.POST("/auth/verify", handlerV1.Verify)
-	apiV1.POST("/auth/login", handlerV1.Login)
-	apiV1.POST("/auth/forgot-password", handlerV1.ForgotPassword)
-	apiV1.POST("/auth/verify-forgot-password", handlerV1.VerifyForgotPassword)
-	apiV1.POST("/auth/update-password",handlerV1.AuthMiddleware("auth","update-password"),  handlerV1.UpdatePassword)
-	
+	auth := apiV1.Group("/auth",
+		v1.Deadline(opt.Cfg.AuthTimeout),
+		handlerV1.RateLimit("auth", opt.Cfg.AuthRateLimit),
+	)
+	auth.POST("/register", handlerV1.Register)
+	auth.POST("/verify", handlerV1.Verify)
+	auth.POST("/login", handlerV1.Login)
+	auth.POST("/forgot-password", handlerV1.ForgotPassword)
+	auth.POST("/verify-forgot-password", handlerV1.VerifyForgotPassword)
+	auth.POST("/update-password", handlerV1.AuthMiddleware("auth", "update-password"), handlerV1.UpdatePassword)
+	auth.POST("/refresh", handlerV1.Refresh)
+	auth.POST("/logout", handlerV1.AuthMiddleware("auth", "logout"), handlerV1.Logout)
+	auth.POST("/logout-all", handlerV1.AuthMiddleware("auth", "logout-all"), handlerV1.LogoutAll)
+
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	return router, nil
+}
+
+// NewInternal builds the router served on the metrics port. It is meant
+// for operators and scrapers only and must not be exposed publicly. Even
+// so, /admin is only served to superadmins, authenticated the same way as
+// on the public router.
+func NewInternal(opt *RouterOptions) *gin.Engine {
+	router := gin.New()
+
+	handlerV1 := v1.New(&v1.HandlerV1Options{
+		Cfg:        opt.Cfg,
+		GrpcClient: &opt.GrpcClient,
+		Catalog:    opt.Catalog,
+		Tokens:     opt.Tokens,
+		Policy:     opt.Policy,
+		Store:      opt.Store,
+	})
+
+	router.Use(
+		gin.Recovery(),
+		handlerV1.Localize(),
+	)
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	admin := router.Group("/admin",
+		v1.Deadline(opt.Cfg.AuthTimeout),
+		handlerV1.AuthMiddleware("admin", "get"),
+		handlerV1.RequireSuperadmin(),
+	)
+	admin.GET("/breakers", handlerV1.GetBreakers)
+	admin.GET("/policy/:role", handlerV1.GetPolicy)
+
 	return router
 }
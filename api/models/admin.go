@@ -0,0 +1,23 @@
+package models
+
+type BreakerStatus struct {
+	Backend           string `json:"backend"`
+	State             string `json:"state"`
+	Failures          int    `json:"consecutive_failures"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+}
+
+type GetBreakersResponse struct {
+	Breakers []BreakerStatus `json:"breakers"`
+}
+
+type PolicyRule struct {
+	Resource  string   `json:"resource"`
+	Actions   []string `json:"actions"`
+	Condition string   `json:"condition,omitempty"`
+}
+
+type GetPolicyResponse struct {
+	Role  string       `json:"role"`
+	Rules []PolicyRule `json:"rules"`
+}
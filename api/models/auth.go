@@ -4,7 +4,6 @@ type RegisterRequest struct {
 	FirstName string `json:"first_name" binding:"required,min=2,max=30"`
 	LastName  string `json:"last_name" binding:"required,min=2,max=30"`
 	Gender    string `json:"gender" binding:"required,oneof=male female" default:"male"`
-	Type      string `json:"type" binding:"required,oneof=superadmin user admin" default:"user"`
 	Email     string `json:"email" binding:"required,email"`
 	Password  string `json:"password" binding:"required,min=6,max=16"`
 }
@@ -14,11 +13,12 @@ type AuthResponse struct {
 	FirstName   string `json:"first_name"`
 	LastName    string `json:"last_name"`
 	Email       string `json:"email"`
-	Password    string `json:"password"`
 	Username    string `json:"username"`
 	Type        string `json:"type"`
 	CreatedAt   string `json:"created_at"`
 	AccessToken string `json:"access_token"`
+	// RefreshToken is only issued when refresh tokens are enabled.
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 type LoginRequest struct {
@@ -38,3 +38,18 @@ type ForgotPasswordRequest struct {
 type UpdatePasswordRequest struct {
 	Password string `json:"password" binding:"required"`
 }
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	// RefreshToken, when given, is revoked together with the access token.
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    string `json:"expires_at"`
+}
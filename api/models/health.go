@@ -0,0 +1,14 @@
+package models
+
+type HealthResponse struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyHealth `json:"dependencies,omitempty"`
+}
+
+// DependencyHealth is the state of a single backend the gateway relies on.
+type DependencyHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	State  string `json:"state"`
+	Error  string `json:"error,omitempty"`
+}
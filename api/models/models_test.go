@@ -0,0 +1,173 @@
+package models_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samandar2605/medium_api_gateway/api/models"
+)
+
+// responseModels are the models handlers write to clients.
+var responseModels = []interface{}{
+	models.AuthResponse{},
+	models.TokenResponse{},
+	models.User{},
+	models.GetAllUsersResponse{},
+	models.Post{},
+	models.PostFull{},
+	models.PostLikeInfo{},
+	models.SectionError{},
+	models.GetAllPostsResponse{},
+	models.Comment{},
+	models.GetAllCommentsResponse{},
+	models.Category{},
+	models.GetAllCategoriesResponse{},
+	models.Like{},
+	models.ErrorResponse{},
+	models.FieldError{},
+	models.ProblemDetails{},
+	models.ResponseOK{},
+	models.HealthResponse{},
+	models.DependencyHealth{},
+	models.GetBreakersResponse{},
+	models.BreakerStatus{},
+	models.GetPolicyResponse{},
+	models.PolicyRule{},
+}
+
+// requestModels are only ever read from requests, so they may carry
+// passwords.
+var requestModels = []string{
+	"RegisterRequest",
+	"LoginRequest",
+	"VerifyRequest",
+	"ForgotPasswordRequest",
+	"UpdatePasswordRequest",
+	"RefreshTokenRequest",
+	"LogoutRequest",
+	"CreateUserRequest",
+	"UpdateUserRequest",
+	"GetAllUserParams",
+	"GetAllParams",
+	"CreatePostRequest",
+	"ChangePost",
+	"GetAllPostsParams",
+	"CreateComment",
+	"UpdateComment",
+	"GetAllCommentsParams",
+	"CreateCategoryRequest",
+	"GetAllCategoriesRequest",
+	"CreateOrUpdateLikeRequest",
+}
+
+// passwordFields returns the JSON paths of the fields of t, at any depth,
+// whose names mention a password.
+func passwordFields(t reflect.Type, path string, seen map[reflect.Type]bool) []string {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return passwordFields(t.Elem(), path, seen)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var found []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// encoding/json still emits the exported fields of embedded
+		// unexported structs.
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && field.Anonymous {
+			// Fields of embedded structs are emitted as if they were
+			// the outer struct's own.
+			found = append(found, passwordFields(field.Type, path, seen)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if strings.Contains(strings.ToLower(name), "password") {
+			found = append(found, path+"."+name)
+		}
+		found = append(found, passwordFields(field.Type, path+"."+name, seen)...)
+	}
+	return found
+}
+
+func TestResponsesHaveNoPasswords(t *testing.T) {
+	for _, model := range responseModels {
+		typ := reflect.TypeOf(model)
+		if found := passwordFields(typ, typ.Name(), map[reflect.Type]bool{}); len(found) > 0 {
+			t.Errorf("%s emits %v", typ.Name(), found)
+		}
+	}
+}
+
+func TestPasswordFieldsFindsNestedFields(t *testing.T) {
+	type credentials struct {
+		Hash string `json:"password_hash"`
+	}
+	type nested struct {
+		credentials
+		Password string `json:"-"`
+		Users    []*struct {
+			Password string
+		} `json:"users"`
+		ByName map[string]credentials `json:"by_name"`
+	}
+
+	found := passwordFields(reflect.TypeOf(nested{}), "nested", map[reflect.Type]bool{})
+	want := []string{"nested.password_hash", "nested.users.Password", "nested.by_name.password_hash"}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("found %v, want %v", found, want)
+	}
+}
+
+// TestEveryModelIsChecked makes sure a new model can't be left out of
+// responseModels by accident.
+func TestEveryModelIsChecked(t *testing.T) {
+	known := make(map[string]bool)
+	for _, model := range responseModels {
+		known[reflect.TypeOf(model).Name()] = true
+	}
+	for _, name := range requestModels {
+		known[name] = true
+	}
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range pkgs["models"].Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				name := spec.(*ast.TypeSpec).Name.Name
+				if ast.IsExported(name) && !known[name] {
+					t.Errorf("%s is neither in responseModels nor in requestModels", name)
+				}
+			}
+		}
+	}
+}
@@ -17,6 +17,24 @@ type PostLikeInfo struct {
 	DislikesCount int64 `json:"dislikes_count"`
 }
 
+// PostFull is a post together with everything needed to show it. Sections
+// that could not be loaded are left null and explained in Errors, keyed by
+// the section's JSON name.
+type PostFull struct {
+	Post     Post                    `json:"post"`
+	Author   *User                   `json:"author"`
+	Category *Category               `json:"category"`
+	Comments *GetAllCommentsResponse `json:"comments"`
+	Likes    *PostLikeInfo           `json:"likes"`
+	Errors   map[string]SectionError `json:"errors,omitempty"`
+}
+
+// SectionError tells why a section of a composite response is missing.
+type SectionError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type CreatePostRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
@@ -1,9 +1,31 @@
 package models
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error   string       `json:"error"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected, either by
+// the gateway's own validation or by a backend service.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProblemDetails is the RFC 7807 error body sent to clients that accept
+// application/problem+json.
+type ProblemDetails struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
 }
 
 type ResponseOK struct {
 	Message string `json:"message"`
-}
\ No newline at end of file
+}
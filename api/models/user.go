@@ -1,16 +1,17 @@
 package models
 
+// User is how a user is shown to clients. It is never bound from a
+// request and must not carry credentials; requests have their own models.
 type User struct {
 	ID              int64  `json:"id"`
-	FirstName       string `json:"first_name" binding:"required,min=2,max=30"`
-	LastName        string `json:"last_name" binding:"required,min=2,max=30"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
 	PhoneNumber     string `json:"phone_number"`
-	Email           string `json:"email" binding:"required,email"`
-	Gender          string `json:"gender" binding:"oneof=male female"`
+	Email           string `json:"email"`
+	Gender          string `json:"gender"`
 	Username        string `json:"username"`
 	ProfileImageUrl string `json:"profile_image_url"`
-	Type            string `json:"type" binding:"required,oneof=user superadmin"`
-	Password        string `json:"password" binding:"required,min=6,max=16"`
+	Type            string `json:"type"`
 	CreatedAt       string `json:"created_at"`
 }
 
@@ -33,7 +34,6 @@ type UpdateUserRequest struct {
 	Gender          string `json:"gender" binding:"oneof=male female"`
 	Username        string `json:"username"`
 	ProfileImageUrl string `json:"profile_image_url"`
-	Type            string `json:"type" binding:"required,oneof=superadmin user"`
 }
 
 type GetAllUsersResponse struct {
@@ -52,4 +52,3 @@ type GetAllParams struct {
 	Page   int32  `json:"page" binding:"required" default:"1"`
 	Search string `json:"search"`
 }
-
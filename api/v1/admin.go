@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+)
+
+// RequireSuperadmin lets only superadmins through. It goes after
+// AuthMiddleware, so that a policy granting others the admin resource, or
+// user_service deciding for a user type the policy doesn't know, still
+// can't open the admin endpoints to them.
+func (h *handlerV1) RequireSuperadmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, err := h.GetAuthPayload(c)
+		if err != nil {
+			writeError(c, http.StatusUnauthorized, err)
+			return
+		}
+		if payload.UserType != userTypeSuperadmin {
+			writeError(c, http.StatusForbidden, ErrNotAllowed)
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetBreakers shows the circuit breaker state of every backend. It is
+// served to superadmins on the internal router only.
+func (h *handlerV1) GetBreakers(c *gin.Context) {
+	response := models.GetBreakersResponse{
+		Breakers: make([]models.BreakerStatus, 0),
+	}
+
+	for _, b := range h.grpcClient.BreakerStatus() {
+		response.Breakers = append(response.Breakers, models.BreakerStatus{
+			Backend:           b.Name,
+			State:             b.State.String(),
+			Failures:          b.Failures,
+			RetryAfterSeconds: int(math.Ceil(b.RetryAfter.Seconds())),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPolicy shows the permission rules currently in effect for a user
+// type. It is served to superadmins on the internal router only.
+func (h *handlerV1) GetPolicy(c *gin.Context) {
+	role := c.Param("role")
+
+	rules, ok := h.policy.Rules(role)
+	if !ok {
+		writeError(c, http.StatusNotFound, fmt.Errorf("no rules for role %q, known roles: %s",
+			role, strings.Join(h.policy.Roles(), ", ")))
+		return
+	}
+
+	response := models.GetPolicyResponse{
+		Role:  role,
+		Rules: make([]models.PolicyRule, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		response.Rules = append(response.Rules, models.PolicyRule{
+			Resource:  rule.Resource,
+			Actions:   rule.Actions,
+			Condition: rule.Condition,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
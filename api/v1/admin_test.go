@@ -0,0 +1,89 @@
+package v1_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/api"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+	"github.com/samandar2605/medium_api_gateway/pkg/policy"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
+)
+
+func newInternalRouter(t *testing.T, cfg config.Config, client *fakeClient) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	tokens, err := token.NewMaker(testSecretKey, token.DefaultClaimLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := i18n.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol, err := policy.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return api.NewInternal(&api.RouterOptions{
+		Cfg:        &cfg,
+		GrpcClient: client,
+		Catalog:    catalog,
+		Tokens:     tokens,
+		Policy:     pol,
+	})
+}
+
+func TestAdminRequiresSuperadmin(t *testing.T) {
+	router := newInternalRouter(t, config.Config{AuthVerifyMode: config.AuthVerifyLocal}, newFakeClient())
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"user", authHeader(t, 1, "user"), http.StatusForbidden},
+		{"admin", authHeader(t, 2, "admin"), http.StatusForbidden},
+		{"superadmin", authHeader(t, 3, "superadmin"), http.StatusOK},
+	}
+	for _, path := range []string{"/admin/breakers", "/admin/policy/user"} {
+		for _, tt := range tests {
+			if w := serve(router, http.MethodGet, path, "", tt.header); w.Code != tt.want {
+				t.Errorf("%s as %s: status = %d, want %d", path, tt.name, w.Code, tt.want)
+			}
+		}
+	}
+
+	if w := serve(router, http.MethodGet, "/metrics", "", nil); w.Code != http.StatusOK {
+		t.Errorf("/metrics: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// A user type the policy doesn't know is judged by user_service, which
+// mustn't be enough to reach the admin endpoints.
+func TestAdminRejectsUnknownUserTypes(t *testing.T) {
+	client := newFakeClient()
+	client.sessions["moderator"] = &pbu.AuthPayload{Id: "1", UserId: 1, UserType: "moderator"}
+	client.sessions["superadmin"] = &pbu.AuthPayload{Id: "2", UserId: 2, UserType: "superadmin"}
+	router := newInternalRouter(t, config.Config{AuthVerifyMode: config.AuthVerifyRemote}, client)
+
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"moderator", http.StatusForbidden},
+		{"superadmin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		header := http.Header{"Authorization": {"Bearer " + tt.token}}
+		if w := serve(router, http.MethodGet, "/admin/breakers", "", header); w.Code != tt.want {
+			t.Errorf("as %s: status = %d, want %d", tt.token, w.Code, tt.want)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package v1
 
 import (
-	"context"
-	"database/sql"
 	"errors"
-
+	"io"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/samandar2605/medium_api_gateway/api/models"
@@ -30,29 +30,36 @@ func (h *handlerV1) Register(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	user, _ := h.grpcClient.UserService().GetByEmail(context.Background(), &pbu.GetByEmailRequest{
+	_, err = h.grpcClient.UserService().GetByEmail(c.Request.Context(), &pbu.GetByEmailRequest{
 		Email: req.Email,
 	})
-
-	if user != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
+	if err == nil {
+		writeError(c, http.StatusConflict, ErrEmailExists)
+		return
+	}
+	// Only a missing user leaves the email free; anything else means
+	// there is no telling whether it is taken.
+	if status.Code(err) != codes.NotFound {
+		handleGrpcError(c, err)
 		return
 	}
 
-	_, err = h.grpcClient.AuthService().Register(context.Background(), &pbu.RegisterRequest{
+	_, err = h.grpcClient.AuthService().Register(c.Request.Context(), &pbu.RegisterRequest{
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Email:     req.Email,
 		Gender:    req.Gender,
 		Password:  req.Password,
-		Type:      req.Type,
+		// Everyone signing up is a plain user; roles are only
+		// granted by superadmins through CreateUser.
+		Type: userTypeUser,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -69,6 +76,7 @@ func (h *handlerV1) Register(c *gin.Context) {
 // @Produce json
 // @Param data body models.VerifyRequest true "Data"
 // @Success 200 {object} models.AuthResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Verify(c *gin.Context) {
 	var (
@@ -77,40 +85,41 @@ func (h *handlerV1) Verify(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.grpcClient.AuthService().Verify(context.Background(), &pbu.VerifyRequest{
+	if !h.allowAttempt(c, flowVerify, req.Email) {
+		return
+	}
+
+	result, err := h.grpcClient.AuthService().Verify(c.Request.Context(), &pbu.VerifyRequest{
 		Email: req.Email,
 		Code:  req.Code,
 	})
 
 	if err != nil {
-		s, _ := status.FromError(err)
-		if s.Message() == "incorrect_code" {
-			c.JSON(http.StatusBadRequest, errorResponse(ErrIncorrectCode))
-			return
-		} else if s.Message() == "code_expired" {
-			c.JSON(http.StatusBadRequest, errorResponse(ErrCodeExpired))
-			return
-		} else {
-			c.JSON(http.StatusInternalServerError, errorResponse(err))
-			return
+		if failedAttempt(err) {
+			h.countAttempt(c, flowVerify, req.Email)
 		}
+		handleGrpcError(c, err)
+		return
 	}
+	h.clearAttempts(c, flowVerify, req.Email)
 
-	c.JSON(http.StatusCreated, models.AuthResponse{
+	response := models.AuthResponse{
 		ID:          result.Id,
 		FirstName:   result.FirstName,
 		LastName:    result.LastName,
 		Email:       result.Email,
-		Password:    result.Password,
 		Username:    result.Username,
 		Type:        result.Type,
 		CreatedAt:   result.CreatedAt,
 		AccessToken: result.AccessToken,
-	})
+	}
+	h.attachRefreshToken(c, &response)
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // @Router /auth/login [post]
@@ -121,6 +130,7 @@ func (h *handlerV1) Verify(c *gin.Context) {
 // @Produce json
 // @Param data body models.LoginRequest true "Data"
 // @Success 200 {object} models.AuthResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Login(c *gin.Context) {
 	var (
@@ -129,38 +139,47 @@ func (h *handlerV1) Login(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.grpcClient.AuthService().Login(context.Background(), &pbu.VerifyRequest{
+	if !h.allowAttempt(c, flowLogin, req.Email) {
+		return
+	}
+
+	result, err := h.grpcClient.AuthService().Login(c.Request.Context(), &pbu.VerifyRequest{
 		Email: req.Email,
 		Code:  req.Password,
 	})
 	if err != nil {
-		s, _ := status.FromError(err)
-		if s.Code() == codes.NotFound || s.Message() == "incorrect_password" {
-			c.JSON(http.StatusBadRequest, errorResponse(ErrWrongEmailOrPass))
+		if failedAttempt(err) {
+			h.countAttempt(c, flowLogin, req.Email)
+		}
+
+		// Do not reveal whether the email is registered.
+		if status.Code(err) == codes.NotFound {
+			writeError(c, http.StatusBadRequest, ErrWrongEmailOrPass)
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
+	h.clearAttempts(c, flowLogin, req.Email)
 
-	c.JSON(http.StatusCreated, models.AuthResponse{
+	response := models.AuthResponse{
 		ID:          result.Id,
 		FirstName:   result.FirstName,
 		LastName:    result.LastName,
 		Email:       result.Email,
 		Username:    result.Username,
-		Password:    result.Password,
 		Type:        result.Type,
 		CreatedAt:   result.CreatedAt,
 		AccessToken: result.AccessToken,
-	})
+	}
+	h.attachRefreshToken(c, &response)
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // @Router /auth/forgot-password [post]
@@ -171,6 +190,7 @@ func (h *handlerV1) Login(c *gin.Context) {
 // @Produce json
 // @Param data body models.ForgotPasswordRequest true "Data"
 // @Success 200 {object} models.ResponseOK
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) ForgotPassword(c *gin.Context) {
 	var (
@@ -179,26 +199,21 @@ func (h *handlerV1) ForgotPassword(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	_, err = h.grpcClient.AuthService().ForgotPassword(context.Background(), &pbu.UserEmail{
+	// Every request sends an email, so all of them count.
+	if !h.allowAttempt(c, flowForgotPassword, req.Email) {
+		return
+	}
+	h.countAttempt(c, flowForgotPassword, req.Email)
+
+	_, err = h.grpcClient.AuthService().ForgotPassword(c.Request.Context(), &pbu.UserEmail{
 		Email: req.Email,
 	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error: err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -215,6 +230,7 @@ func (h *handlerV1) ForgotPassword(c *gin.Context) {
 // @Produce json
 // @Param data body models.VerifyRequest true "Data"
 // @Success 200 {object} models.AuthResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) VerifyForgotPassword(c *gin.Context) {
 	var (
@@ -223,23 +239,29 @@ func (h *handlerV1) VerifyForgotPassword(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if !h.allowAttempt(c, flowVerifyForgotPassword, req.Email) {
 		return
 	}
 
-	res, err := h.grpcClient.AuthService().VerifyForgotPassword(context.Background(), &pbu.VerifyRequest{
+	res, err := h.grpcClient.AuthService().VerifyForgotPassword(c.Request.Context(), &pbu.VerifyRequest{
 		Code:  req.Code,
 		Email: req.Email,
 	})
 
 	if err != nil {
-		c.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
+		if failedAttempt(err) {
+			h.countAttempt(c, flowVerifyForgotPassword, req.Email)
+		}
+		handleGrpcError(c, err)
 		return
 	}
+	h.clearAttempts(c, flowVerifyForgotPassword, req.Email)
 
-	c.JSON(http.StatusCreated, models.AuthResponse{
+	response := models.AuthResponse{
 		ID:          res.Id,
 		FirstName:   res.FirstName,
 		LastName:    res.LastName,
@@ -248,7 +270,10 @@ func (h *handlerV1) VerifyForgotPassword(c *gin.Context) {
 		Type:        res.Type,
 		CreatedAt:   res.CreatedAt,
 		AccessToken: res.AccessToken,
-	})
+	}
+	h.attachRefreshToken(c, &response)
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // @Security ApiKeyAuth
@@ -268,32 +293,219 @@ func (h *handlerV1) UpdatePassword(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	payload, err := h.GetAuthPayload(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusUnauthorized, err)
 		return
 	}
 
-	_, err = h.grpcClient.AuthService().UpdatePassword(context.Background(), &pbu.NewPassword{
+	_, err = h.grpcClient.AuthService().UpdatePassword(c.Request.Context(), &pbu.NewPassword{
 		UserId:      payload.UserID,
 		Password:    req.Password,
 	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
 		return
 	}
+	h.verified.invalidateUser(payload.UserID)
 
 	c.JSON(http.StatusCreated, models.ResponseOK{
 		Message: "Password has been updated!",
 	})
 }
+
+// attachRefreshToken starts a refresh token family for a user who just
+// authenticated. Failing to do so must not fail the login, so errors are
+// only logged and the response goes out without a refresh token.
+func (h *handlerV1) attachRefreshToken(c *gin.Context, response *models.AuthResponse) {
+	if !h.refreshEnabled() {
+		return
+	}
+
+	refreshToken, err := h.startSession(c.Request.Context(), response.ID)
+	if err != nil {
+		log.Printf("failed to issue refresh token for user %d: %v", response.ID, err)
+		return
+	}
+	response.RefreshToken = refreshToken
+}
+
+// @Router /auth/refresh [post]
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Every refresh token can be used once; reusing one logs out every session started from the same login.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.RefreshTokenRequest true "Data"
+// @Success 200 {object} models.TokenResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) Refresh(c *gin.Context) {
+	var (
+		req models.RefreshTokenRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if !h.refreshEnabled() {
+		writeError(c, http.StatusNotImplemented, ErrRefreshNotConfigured)
+		return
+	}
+
+	session, err := h.lookupRefreshToken(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		h.writeSessionError(c, err)
+		return
+	}
+
+	// Take the user's current email and type rather than the ones they
+	// had when logging in.
+	user, err := h.grpcClient.UserService().Get(c.Request.Context(), &pbu.IdRequest{Id: session.UserID})
+	if status.Code(err) == codes.NotFound {
+		writeError(c, http.StatusUnauthorized, ErrInvalidRefreshToken)
+		return
+	} else if err != nil {
+		handleGrpcError(c, err)
+		return
+	}
+
+	accessToken, claims, err := h.tokens.Create(user.Id, user.Email, user.Type, h.cfg.AccessTokenTTL)
+	if err != nil {
+		h.writeSessionError(c, err)
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(c.Request.Context(), refreshSession{
+		Family:          session.Family,
+		FamilyCreatedAt: session.FamilyCreatedAt,
+		UserID:          session.UserID,
+		ExpiresAt:       time.Now().Add(h.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		h.writeSessionError(c, err)
+		return
+	}
+
+	// Only now that nothing else can fail is the old token used up.
+	if err := h.useRefreshToken(c.Request.Context(), req.RefreshToken, session); err != nil {
+		h.writeSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    claims.ExpiredAt.Format(time.RFC3339),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/logout [post]
+// @Summary Log out
+// @Description Revoke the access token the request is made with and, when given, the refresh token of the same session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.LogoutRequest false "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+func (h *handlerV1) Logout(c *gin.Context) {
+	var (
+		req models.LogoutRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Revoked tokens are remembered in the store.
+	if h.store == nil {
+		writeError(c, http.StatusNotImplemented, ErrLogoutNotConfigured)
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		writeError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.revokeToken(c.Request.Context(), payload); err != nil {
+		h.writeSessionError(c, err)
+		return
+	}
+
+	if req.RefreshToken != "" {
+		session, err := h.lookupRefreshToken(c.Request.Context(), req.RefreshToken)
+		switch {
+		case err == nil && session.UserID == payload.UserID:
+			if err := h.revokeFamily(c.Request.Context(), session.Family); err != nil {
+				h.writeSessionError(c, err)
+				return
+			}
+		case err != nil && !errors.Is(err, ErrInvalidRefreshToken):
+			h.writeSessionError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Logged out",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/logout-all [post]
+// @Summary Log out everywhere
+// @Description Revoke every access and refresh token of the current user.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+func (h *handlerV1) LogoutAll(c *gin.Context) {
+	if h.store == nil {
+		writeError(c, http.StatusNotImplemented, ErrLogoutNotConfigured)
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		writeError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := h.revokeUser(c.Request.Context(), payload.UserID); err != nil {
+		h.writeSessionError(c, err)
+		return
+	}
+	h.verified.invalidateUser(payload.UserID)
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Logged out of all sessions",
+	})
+}
+
+// writeSessionError reports a failure of the refresh token and revocation
+// bookkeeping. Anything but a rejected token is a problem of the gateway.
+func (h *handlerV1) writeSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRefreshToken), errors.Is(err, ErrRefreshTokenReused):
+		writeError(c, http.StatusUnauthorized, err)
+	default:
+		log.Printf("session store error: %v", err)
+		writeError(c, http.StatusInternalServerError, ErrInternal)
+	}
+}
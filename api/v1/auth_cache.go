@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/pkg/cache"
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+)
+
+// verification is who a token belongs to and, when it was verified by
+// user_service, what user_service answered about the resource and action.
+type verification struct {
+	payload       Payload
+	remote        bool
+	hasPermission bool
+}
+
+// verifyCache remembers the answers of VerifyToken so repeated requests
+// with the same token don't each cost a round trip to user_service.
+type verifyCache struct {
+	ttl     time.Duration
+	entries *cache.LRU[string, verification]
+}
+
+func newVerifyCache(size int, ttl time.Duration) *verifyCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &verifyCache{
+		ttl:     ttl,
+		entries: cache.NewLRU[string, verification](size),
+	}
+}
+
+// verifyCacheKey never holds the token itself, only its hash.
+func verifyCacheKey(accessToken, resource, action string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(sum[:]) + "|" + resource + "|" + action
+}
+
+// ownerKey is the key under which the payload of a token is cached,
+// whatever it was verified for.
+func ownerKey(accessToken string) string {
+	return verifyCacheKey(accessToken, "", "")
+}
+
+// owner returns the payload of accessToken if user_service verified it
+// recently.
+func (vc *verifyCache) owner(accessToken string) (Payload, bool) {
+	if vc == nil {
+		return Payload{}, false
+	}
+
+	v, ok := vc.entries.Get(ownerKey(accessToken))
+	return v.payload, ok
+}
+
+func (vc *verifyCache) get(key string) (verification, bool) {
+	if vc == nil {
+		return verification{}, false
+	}
+
+	v, ok := vc.entries.Get(key)
+	if ok {
+		metrics.AuthCacheRequests.WithLabelValues("hit").Inc()
+	} else {
+		metrics.AuthCacheRequests.WithLabelValues("miss").Inc()
+	}
+	return v, ok
+}
+
+// set caches v until the token expires or the configured TTL runs out,
+// whichever comes first. Tokens whose expiry can't be read aren't cached,
+// since they might expire any moment.
+func (vc *verifyCache) set(key string, v verification) {
+	if vc == nil {
+		return
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, v.payload.ExpiredAt)
+	if err != nil {
+		return
+	}
+	ttl := vc.ttl
+	if untilExpiry := time.Until(expiresAt); untilExpiry < ttl {
+		ttl = untilExpiry
+	}
+	vc.entries.Set(key, v, ttl)
+}
+
+// invalidateUser drops everything cached for the tokens of userID, e.g.
+// after the user changed their password.
+func (vc *verifyCache) invalidateUser(userID int64) {
+	if vc == nil {
+		return
+	}
+
+	vc.entries.DeleteFunc(func(_ string, v verification) bool {
+		return v.payload.UserID == userID
+	})
+}
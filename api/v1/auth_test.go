@@ -0,0 +1,124 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/api"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLogoutWithoutStore(t *testing.T) {
+	router := newRouter(t, config.Config{}, newFakeClient())
+
+	for _, path := range []string{"/v1/auth/logout", "/v1/auth/logout-all"} {
+		w := serve(router, http.MethodPost, path, "", authHeader(t, 1, "user"))
+		if w.Code != http.StatusNotImplemented {
+			t.Fatalf("%s: status = %d, want %d: %s", path, w.Code, http.StatusNotImplemented, w.Body)
+		}
+
+		var problem models.ProblemDetails
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatal(err)
+		}
+		if problem.Code != "auth.logout_not_configured" {
+			t.Errorf("%s: code = %q, want %q", path, problem.Code, "auth.logout_not_configured")
+		}
+	}
+}
+
+func TestRefreshSurvivesBackendFailure(t *testing.T) {
+	client := newFakeClient()
+	client.users[1] = &pbu.User{Id: 1, Email: "user@example.com", Type: "user"}
+	client.passwords["user@example.com"] = testPassword
+
+	router := newRouterWith(t, api.RouterOptions{
+		Cfg:        &config.Config{AccessTokenTTL: time.Hour, RefreshTokenTTL: time.Hour},
+		GrpcClient: client,
+		Store:      store.NewMemory(),
+	})
+
+	w := serve(router, http.MethodPost, "/v1/auth/login",
+		fmt.Sprintf(`{"email":"user@example.com","password":%q}`, testPassword), nil)
+	var auth models.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &auth); err != nil || auth.RefreshToken == "" {
+		t.Fatalf("login: no refresh token in %s (%v)", w.Body, err)
+	}
+
+	refresh := func(refreshToken string) (int, string) {
+		w := serve(router, http.MethodPost, "/v1/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, refreshToken), nil)
+		var tokens models.TokenResponse
+		json.Unmarshal(w.Body.Bytes(), &tokens)
+		return w.Code, tokens.RefreshToken
+	}
+
+	client.userErr = status.Error(codes.Unavailable, "user_service is down")
+	if code, _ := refresh(auth.RefreshToken); code == http.StatusOK || code == http.StatusUnauthorized {
+		t.Fatalf("refresh during outage: status = %d, want a server error", code)
+	}
+
+	// The client retries with the same token once user_service is back.
+	code, next := refresh(auth.RefreshToken)
+	if code != http.StatusOK {
+		t.Fatalf("retried refresh: status = %d, want %d", code, http.StatusOK)
+	}
+	if code, _ := refresh(next); code != http.StatusOK {
+		t.Fatalf("refresh with the new token: status = %d, want %d", code, http.StatusOK)
+	}
+
+	// Reuse is still caught once the token really was used.
+	if code, _ := refresh(auth.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("reused refresh token: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
+
+// TestLogoutAllWithRemoteVerification checks that logging out everywhere
+// rejects the tokens user_service verifies, even when it tells their age
+// in a format the gateway doesn't know.
+func TestLogoutAllWithRemoteVerification(t *testing.T) {
+	client := newFakeClient()
+	client.sessions["token-1"] = &pbu.AuthPayload{Id: "1", UserId: 1, UserType: "user",
+		IssuedAt: time.Now().Add(-time.Minute).Format(time.RFC3339)}
+	client.sessions["token-2"] = &pbu.AuthPayload{Id: "2", UserId: 1, UserType: "user",
+		IssuedAt: time.Now().Add(-time.Minute).Format(time.UnixDate)}
+	client.sessions["token-3"] = &pbu.AuthPayload{Id: "3", UserId: 1, UserType: "user",
+		IssuedAt: time.Now().Add(time.Minute).Format(time.RFC3339)}
+
+	router := newRouterWith(t, api.RouterOptions{
+		Cfg:        &config.Config{AuthVerifyMode: config.AuthVerifyRemote, RefreshTokenTTL: time.Hour},
+		GrpcClient: client,
+		Store:      store.NewMemory(),
+	})
+
+	logoutAll := func(accessToken string) int {
+		header := http.Header{"Authorization": {"Bearer " + accessToken}}
+		return serve(router, http.MethodPost, "/v1/auth/logout-all", "", header).Code
+	}
+
+	if code := logoutAll("token-1"); code != http.StatusOK {
+		t.Fatalf("logout-all: status = %d, want %d", code, http.StatusOK)
+	}
+
+	tests := []struct {
+		accessToken string
+		want        int
+	}{
+		{"token-1", http.StatusUnauthorized},
+		{"token-2", http.StatusUnauthorized},
+		// Issued after the logout.
+		{"token-3", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := logoutAll(tt.accessToken); code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.accessToken, code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Flows guarded against brute force. Each keeps its own counters, so
+// failing to log in doesn't lock a user out of resetting their password.
+const (
+	flowLogin                = "login"
+	flowVerify               = "verify"
+	flowForgotPassword       = "forgot_password"
+	flowVerifyForgotPassword = "verify_forgot_password"
+)
+
+const (
+	attemptsPrefix = "attempts:"
+	lockoutPrefix  = "lockout:"
+
+	scopeEmail = "email"
+	scopeIP    = "ip"
+)
+
+// attemptSubject is who attempts are counted against: an email or a client
+// IP.
+type attemptSubject struct {
+	scope       string
+	key         string
+	maxAttempts int
+}
+
+func (h *handlerV1) attemptSubjects(c *gin.Context, email string) []attemptSubject {
+	return []attemptSubject{
+		{
+			scope: scopeEmail,
+			// Emails are hashed so the store doesn't collect them.
+			key:         hashToken(strings.ToLower(strings.TrimSpace(email))),
+			maxAttempts: h.cfg.BruteForceEmailMaxAttempts,
+		},
+		{
+			scope: scopeIP,
+			// Only TrustedProxies may name another address, so
+			// clients can't pick a fresh one for every guess.
+			key:         c.ClientIP(),
+			maxAttempts: h.cfg.BruteForceIPMaxAttempts,
+		},
+	}
+}
+
+func attemptsKey(flow string, s attemptSubject) string {
+	return attemptsPrefix + flow + ":" + s.scope + ":" + s.key
+}
+
+func lockoutKey(flow string, s attemptSubject) string {
+	return lockoutPrefix + flow + ":" + s.scope + ":" + s.key
+}
+
+// allowAttempt rejects the request with 429 while the email or the client
+// IP has to wait before trying flow again. The response has then already
+// been written.
+func (h *handlerV1) allowAttempt(c *gin.Context, flow, email string) bool {
+	if h.store == nil {
+		return true
+	}
+
+	var wait time.Duration
+	for _, s := range h.attemptSubjects(c, email) {
+		if s.maxAttempts <= 0 {
+			continue
+		}
+
+		until, err := h.lockedUntil(c.Request.Context(), lockoutKey(flow, s))
+		if err != nil {
+			// Better to let a few guesses through than to lock
+			// everyone out while the store is down.
+			log.Printf("failed to check %s lockout: %v", flow, err)
+			continue
+		}
+		if d := time.Until(until); d > wait {
+			wait = d
+		}
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	c.Header("Retry-After", ceilSeconds(wait))
+	writeError(c, http.StatusTooManyRequests, ErrTooManyAttempts)
+	return false
+}
+
+func (h *handlerV1) lockedUntil(ctx context.Context, key string) (time.Time, error) {
+	value, err := h.store.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	until, _ := time.Parse(time.RFC3339Nano, value)
+	return until, nil
+}
+
+// countAttempt counts an attempt at flow against the email and the client
+// IP, and makes them wait as configured before the next one.
+func (h *handlerV1) countAttempt(c *gin.Context, flow, email string) {
+	if h.store == nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	for _, s := range h.attemptSubjects(c, email) {
+		if s.maxAttempts <= 0 {
+			continue
+		}
+
+		n, err := h.store.Incr(ctx, attemptsKey(flow, s), h.cfg.BruteForceWindow)
+		if err != nil {
+			log.Printf("failed to count %s attempt: %v", flow, err)
+			continue
+		}
+
+		delay := h.attemptDelay(s, n)
+		if delay <= 0 {
+			continue
+		}
+		if n >= int64(s.maxAttempts) {
+			metrics.AuthLockouts.WithLabelValues(flow, s.scope).Inc()
+		}
+
+		until := time.Now().Add(delay)
+		if err := h.store.Set(ctx, lockoutKey(flow, s), until.Format(time.RFC3339Nano), delay); err != nil {
+			log.Printf("failed to lock out of %s: %v", flow, err)
+		}
+	}
+}
+
+// attemptDelay returns how long s has to wait after its nth attempt.
+func (h *handlerV1) attemptDelay(s attemptSubject, n int64) time.Duration {
+	lockout := h.cfg.BruteForceLockout
+	if n >= int64(s.maxAttempts) {
+		return lockout
+	}
+
+	// IPs are only ever locked out; slowing them down would slow down
+	// everyone behind the same NAT.
+	base := h.cfg.BruteForceBaseDelay
+	if s.scope != scopeEmail || n < 2 || base <= 0 {
+		return 0
+	}
+
+	shift := n - 2
+	if shift > 30 || base<<shift > lockout {
+		return lockout
+	}
+	return base << shift
+}
+
+// clearAttempts forgets the attempts of email at flow after it succeeded.
+// The client IP's count is kept, an attacker could reset it with an
+// account of their own otherwise.
+func (h *handlerV1) clearAttempts(c *gin.Context, flow, email string) {
+	if h.store == nil {
+		return
+	}
+
+	s := h.attemptSubjects(c, email)[0]
+	if err := h.store.Delete(c.Request.Context(), attemptsKey(flow, s)); err != nil {
+		log.Printf("failed to clear %s attempts: %v", flow, err)
+	}
+}
+
+// failedAttempt reports whether err, returned by an authentication rpc,
+// means the credentials were wrong rather than the backend being in
+// trouble.
+func failedAttempt(err error) bool {
+	s := status.Convert(err)
+	if _, ok := backendErrors[s.Message()]; ok {
+		return true
+	}
+
+	switch s.Code() {
+	case codes.InvalidArgument, codes.NotFound, codes.PermissionDenied,
+		codes.Unauthenticated, codes.FailedPrecondition:
+		return true
+	}
+	return false
+}
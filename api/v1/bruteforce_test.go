@@ -0,0 +1,169 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/api"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/config"
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+)
+
+// lockoutStore is the in-memory store, recording how long every lockout
+// it is asked to keep lasts.
+type lockoutStore struct {
+	*store.Memory
+
+	mu       sync.Mutex
+	lockouts []time.Duration
+}
+
+func (s *lockoutStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if strings.HasPrefix(key, "lockout:") {
+		s.mu.Lock()
+		s.lockouts = append(s.lockouts, ttl)
+		s.mu.Unlock()
+	}
+	return s.Memory.Set(ctx, key, value, ttl)
+}
+
+func (s *lockoutStore) recorded() []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Duration(nil), s.lockouts...)
+}
+
+const testPassword = "secret123"
+
+func newLoginRouter(t *testing.T, cfg config.Config) (http.Handler, *lockoutStore) {
+	t.Helper()
+
+	client := newFakeClient()
+	client.passwords["user@example.com"] = testPassword
+	if cfg.BruteForceWindow == 0 {
+		cfg.BruteForceWindow = time.Minute
+	}
+	if cfg.BruteForceLockout == 0 {
+		cfg.BruteForceLockout = time.Minute
+	}
+
+	kv := &lockoutStore{Memory: store.NewMemory()}
+	return newRouterWith(t, api.RouterOptions{Cfg: &cfg, GrpcClient: client, Store: kv}), kv
+}
+
+func login(router http.Handler, remoteAddr, email, password string, header http.Header) int {
+	body := fmt.Sprintf(`{"email":%q,"password":%q}`, email, password)
+	return serveFrom(router, remoteAddr, http.MethodPost, "/v1/auth/login", body, header).Code
+}
+
+func TestLoginDelayGrowsUntilLockout(t *testing.T) {
+	const base = 50 * time.Millisecond
+	router, kv := newLoginRouter(t, config.Config{
+		BruteForceEmailMaxAttempts: 5,
+		BruteForceBaseDelay:        base,
+	})
+
+	// The first failure costs nothing, every later one doubles the wait.
+	for i := 1; i <= 4; i++ {
+		if code := login(router, "192.0.2.1:1234", "user@example.com", "wrong-password", nil); code == http.StatusTooManyRequests {
+			t.Fatalf("failure %d was rejected as too many attempts", i)
+		}
+
+		lockouts := kv.recorded()
+		if len(lockouts) == 0 {
+			continue
+		}
+		// Even the right password has to wait.
+		if code := login(router, "192.0.2.1:1234", "user@example.com", testPassword, nil); code != http.StatusTooManyRequests {
+			t.Fatalf("attempt right after failure %d: status = %d, want %d", i, code, http.StatusTooManyRequests)
+		}
+		time.Sleep(lockouts[len(lockouts)-1] + 10*time.Millisecond)
+	}
+
+	want := []time.Duration{base, 2 * base, 4 * base}
+	if got := kv.recorded(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("delays = %v, want %v", got, want)
+	}
+
+	// The fifth failure reaches the limit.
+	login(router, "192.0.2.1:1234", "user@example.com", "wrong-password", nil)
+	want = append(want, time.Minute)
+	if got := kv.recorded(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("delays = %v, want %v", got, want)
+	}
+
+	w := serveFrom(router, "192.0.2.1:1234", http.MethodPost, "/v1/auth/login",
+		fmt.Sprintf(`{"email":"user@example.com","password":%q}`, testPassword), nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want %q", got, "60")
+	}
+	var problem models.ProblemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Code != "auth.too_many_attempts" {
+		t.Errorf("code = %q, want %q", problem.Code, "auth.too_many_attempts")
+	}
+}
+
+func TestLoginSuccessClearsAttempts(t *testing.T) {
+	router, _ := newLoginRouter(t, config.Config{BruteForceEmailMaxAttempts: 3})
+
+	fail := func() {
+		login(router, "192.0.2.1:1234", "user@example.com", "wrong-password", nil)
+	}
+
+	fail()
+	fail()
+	if code := login(router, "192.0.2.1:1234", "user@example.com", testPassword, nil); code != http.StatusCreated {
+		t.Fatalf("login: status = %d, want %d", code, http.StatusCreated)
+	}
+
+	// Without the success in between, the third failure would lock the
+	// email out.
+	fail()
+	fail()
+	if code := login(router, "192.0.2.1:1234", "user@example.com", testPassword, nil); code != http.StatusCreated {
+		t.Fatalf("login after clearing: status = %d, want %d", code, http.StatusCreated)
+	}
+
+	fail()
+	fail()
+	fail()
+	if code := login(router, "192.0.2.1:1234", "user@example.com", testPassword, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("login after 3 failures: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+// TestLoginLocksOutIP checks that failures from one address count against
+// it whatever emails they try and whatever it names in X-Forwarded-For.
+func TestLoginLocksOutIP(t *testing.T) {
+	router, _ := newLoginRouter(t, config.Config{BruteForceIPMaxAttempts: 3})
+
+	for i := 1; i <= 3; i++ {
+		header := http.Header{"X-Forwarded-For": {fmt.Sprintf("198.51.100.%d", i)}}
+		email := fmt.Sprintf("guess%d@example.com", i)
+		if code := login(router, "192.0.2.1:1234", email, "wrong-password", header); code == http.StatusTooManyRequests {
+			t.Fatalf("failure %d was rejected as too many attempts", i)
+		}
+	}
+
+	header := http.Header{"X-Forwarded-For": {"198.51.100.4"}}
+	if code := login(router, "192.0.2.1:1234", "user@example.com", testPassword, header); code != http.StatusTooManyRequests {
+		t.Errorf("locked out address: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := login(router, "192.0.2.2:1234", "user@example.com", testPassword, nil); code != http.StatusCreated {
+		t.Errorf("other address: status = %d, want %d", code, http.StatusCreated)
+	}
+}
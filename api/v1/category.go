@@ -1,7 +1,6 @@
 package v1
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 
@@ -28,17 +27,18 @@ func (h *handlerV1) CreateCategory(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	resp, err := h.grpcClient.CategoryService().Create(context.Background(), &pbp.Category{
+	resp, err := h.grpcClient.CategoryService().Create(c.Request.Context(), &pbp.Category{
 		Title: req.Title,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
+	h.invalidateResponses(c, CacheNamespaceCategories)
 
 	c.JSON(http.StatusCreated, models.Category{
 		Id:        resp.Id,
@@ -54,24 +54,22 @@ func (h *handlerV1) CreateCategory(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-None-Match header string false "ETag of the copy the client has"
 // @Success 200 {object} models.Category
+// @Success 304 "Not Modified"
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) GetCategory(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	resp, err := h.grpcClient.CategoryService().Get(context.Background(), &pbp.IdByRequest{
+	resp, err := h.grpcClient.CategoryService().Get(c.Request.Context(), &pbp.IdByRequest{
 		Id: int64(id),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -94,21 +92,17 @@ func (h *handlerV1) GetCategory(c *gin.Context) {
 func (h *handlerV1) GetCategoryAll(ctx *gin.Context) {
 	queryParams, err := validateGetCategoryQuery(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	resp, err := h.grpcClient.CategoryService().GetAll(context.Background(), &pbp.GetCategoryRequest{
+	resp, err := h.grpcClient.CategoryService().GetAll(ctx.Request.Context(), &pbp.GetCategoryRequest{
 		Page:   queryParams.Page,
 		Limit:  queryParams.Limit,
 		Search: queryParams.Search,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
 	result := models.GetAllCategoriesResponse{
@@ -169,30 +163,25 @@ func (h *handlerV1) UpdateCategory(ctx *gin.Context) {
 
 	err := ctx.ShouldBindJSON(&b)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	category, err := h.grpcClient.CategoryService().Update(context.Background(), &pbp.Category{
+	category, err := h.grpcClient.CategoryService().Update(ctx.Request.Context(), &pbp.Category{
 		Id:    int64(id),
 		Title: b.Title,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to create category",
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
+	h.invalidateResponses(ctx, CacheNamespaceCategories)
 
 	ctx.JSON(http.StatusOK, category)
 }
@@ -204,27 +193,28 @@ func (h *handlerV1) UpdateCategory(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
 // @Failure 500 {object} models.ErrorResponse
 // @Router /categories/{id} [delete]
 func (h *handlerV1) DeleteCategory(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to convert",
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	_, err = h.grpcClient.CategoryService().Delete(context.Background(), &pbp.IdByRequest{
+	_, err = h.grpcClient.CategoryService().Delete(ctx.Request.Context(), &pbp.IdByRequest{
 		Id: int64(id),
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to Delete method",
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "successful delete method",
+	h.invalidateResponses(ctx, CacheNamespaceCategories)
+	// Posts may have gone with the category.
+	h.invalidateResponses(ctx, CacheNamespacePosts)
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "successful delete method",
 	})
 }
@@ -1,7 +1,6 @@
 package v1
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 
@@ -17,24 +16,22 @@ import (
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-None-Match header string false "ETag of the copy the client has"
 // @Success 200 {object} models.Comment
+// @Success 304 "Not Modified"
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) GetComment(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	resp, err := h.grpcClient.CommentService().Get(context.Background(), &pbp.IdWithRequest{
+	resp, err := h.grpcClient.CommentService().Get(c.Request.Context(), &pbp.IdWithRequest{
 		Id: int64(id),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -66,29 +63,23 @@ func (h *handlerV1) CreateComment(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	payload, err := h.GetAuthPayload(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusUnauthorized, err)
 		return
 	}
 
-	resp, err := h.grpcClient.CommentService().Create(context.Background(), &pbp.CreateCommentRequest{
+	resp, err := h.grpcClient.CommentService().Create(c.Request.Context(), &pbp.CreateCommentRequest{
 		PostId:      int64(req.PostId),
 		UserId:      int64(payload.UserID),
 		Description: req.Description,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -113,13 +104,11 @@ func (h *handlerV1) CreateComment(c *gin.Context) {
 func (h *handlerV1) GetAllComment(c *gin.Context) {
 	req, err := commentsParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.grpcClient.CommentService().GetAll(context.Background(), &pbp.GetCommentQuery{
+	result, err := h.grpcClient.CommentService().GetAll(c.Request.Context(), &pbp.GetCommentQuery{
 		Page:       int64(req.Page),
 		Limit:      int64(req.Limit),
 		PostId:     int64(req.PostID),
@@ -127,9 +116,7 @@ func (h *handlerV1) GetAllComment(c *gin.Context) {
 		UserId:     int64(req.UserID),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -219,56 +206,44 @@ func parseCommentModel(Comment *pbp.Comment) models.Comment {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the version the update is based on"
 // @Param comment body models.UpdateComment true "comment"
 // @Success 200 {object} models.Comment
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /comments/{id} [put]
 func (h *handlerV1) UpdateComment(ctx *gin.Context) {
 	var b models.UpdateComment
 	err := ctx.ShouldBindJSON(&b)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	payload, err := h.GetAuthPayload(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+	target, ok := h.ownedComment(ctx, int64(id))
+	if !ok || !checkIfMatch(ctx, parseCommentModel(target)) {
 		return
 	}
 
-	comment, err := h.grpcClient.CommentService().Update(context.Background(), &pbp.Comment{
+	comment, err := h.grpcClient.CommentService().Update(ctx.Request.Context(), &pbp.Comment{
 		Id:          int64(id),
 		Description: b.Description,
-		UserId:      payload.UserID,
+		UserId:      target.UserId,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, models.Comment{
-		Id:          int(comment.Id),
-		PostId:      int(comment.PostId),
-		UserId:      int(payload.UserID),
-		Description: comment.Description,
-		CreatedAt:   comment.CreatedAt,
-		UpdatedAt:   comment.UpdatedAt,
-	})
+	updated := parseCommentModel(comment)
+	setETag(ctx, updated)
+	ctx.JSON(http.StatusOK, updated)
 }
 
 // @Security ApiKeyAuth
@@ -278,25 +253,29 @@ func (h *handlerV1) UpdateComment(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the version to delete"
+// @Success 200 {object} models.ResponseOK
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /comments/{id} [delete]
 func (h *handlerV1) DeleteComment(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to convert",
-		})
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	target, ok := h.ownedComment(ctx, int64(id))
+	if !ok || !checkIfMatch(ctx, parseCommentModel(target)) {
 		return
 	}
 
-	_, err = h.grpcClient.CommentService().Delete(context.Background(), &pbp.IdWithRequest{Id: int64(id)})
+	_, err = h.grpcClient.CommentService().Delete(ctx.Request.Context(), &pbp.IdWithRequest{Id: int64(id)})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to Delete method",
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "successful delete method",
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "successful delete method",
 	})
 }
@@ -0,0 +1,266 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// statusClientClosedRequest is the de facto status for requests the
+	// client gave up on before a response was ready.
+	statusClientClosedRequest = 499
+
+	mimeProblemJSON = "application/problem+json"
+	problemTypeBase = "urn:medium:problem:"
+)
+
+// errorCodes holds the stable, machine readable codes of the gateway's own
+// errors. Clients may rely on them, so existing codes must never change.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrWrongEmailOrPass, "auth.wrong_credentials"},
+	{ErrEmailExists, "auth.email_exists"},
+	{ErrUserNotVerified, "auth.user_not_verified"},
+	{ErrIncorrectCode, "auth.incorrect_code"},
+	{ErrCodeExpired, "auth.code_expired"},
+	{ErrNotAllowed, "auth.not_allowed"},
+	{ErrForbidden, "auth.forbidden"},
+	{ErrUnauthorized, "auth.unauthorized"},
+	{ErrMissingToken, "auth.missing_token"},
+	{token.ErrInvalidToken, "auth.invalid_token"},
+	{token.ErrExpiredToken, "auth.token_expired"},
+	{ErrRequestTimeout, "gateway.timeout"},
+	{ErrRequestCanceled, "gateway.canceled"},
+	{ErrServiceUnavailable, "gateway.service_unavailable"},
+	{ErrInternal, "gateway.internal"},
+	{ErrValidation, "gateway.validation_failed"},
+	{ErrTokenRevoked, "auth.token_revoked"},
+	{ErrInvalidRefreshToken, "auth.invalid_refresh_token"},
+	{ErrRefreshTokenReused, "auth.refresh_token_reused"},
+	{ErrRefreshNotConfigured, "auth.refresh_not_configured"},
+	{ErrLogoutNotConfigured, "auth.logout_not_configured"},
+	{ErrTooManyAttempts, "auth.too_many_attempts"},
+	{ErrRateLimited, "gateway.rate_limited"},
+	{ErrPreconditionFailed, "gateway.precondition_failed"},
+}
+
+// MessageKeys returns every key the gateway looks its messages up by in
+// the i18n catalogs: the codes of its own errors and the validation
+// messages.
+func MessageKeys() []string {
+	keys := make([]string, 0, len(errorCodes)+len(validationMessageKeys))
+	for _, e := range errorCodes {
+		keys = append(keys, e.code)
+	}
+	return append(keys, validationMessageKeys...)
+}
+
+// statusReasons names the reason part of the code given to errors that are
+// not listed in errorCodes, e.g. post.not_found.
+var statusReasons = map[int]string{
+	http.StatusBadRequest:          "invalid_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusPreconditionFailed:  "precondition_failed",
+	http.StatusTooManyRequests:     "too_many_requests",
+	statusClientClosedRequest:      "canceled",
+	http.StatusInternalServerError: "internal",
+	http.StatusNotImplemented:      "not_implemented",
+	http.StatusServiceUnavailable:  "unavailable",
+	http.StatusGatewayTimeout:      "timeout",
+}
+
+// resourceNames maps the first path segment of a route to the resource
+// prefix used in error codes.
+var resourceNames = map[string]string{
+	"auth":       "auth",
+	"users":      "user",
+	"posts":      "post",
+	"comments":   "comment",
+	"likes":      "like",
+	"categories": "category",
+}
+
+// backendErrors maps the well-known messages the backend services put into
+// their statuses to the gateway's own errors.
+var backendErrors = map[string]error{
+	"incorrect_code":     ErrIncorrectCode,
+	"code_expired":       ErrCodeExpired,
+	"incorrect_password": ErrWrongEmailOrPass,
+}
+
+var grpcToHTTPStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           statusClientClosedRequest,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// grpcError translates an error returned by a backend rpc into the HTTP
+// status code and the error that is shown to the client.
+func grpcError(err error) (int, error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, ErrRequestTimeout
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, ErrRequestCanceled
+	}
+
+	s := status.Convert(err)
+	if e, ok := backendErrors[s.Message()]; ok {
+		return http.StatusBadRequest, e
+	}
+
+	code, ok := grpcToHTTPStatus[s.Code()]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	if fields := badRequestDetails(s); len(fields) > 0 && code == http.StatusBadRequest {
+		return code, &validationError{fields: fields}
+	}
+
+	switch s.Code() {
+	case codes.DeadlineExceeded:
+		return code, ErrRequestTimeout
+	case codes.Canceled:
+		return code, ErrRequestCanceled
+	case codes.Unavailable:
+		return code, ErrServiceUnavailable
+	}
+
+	if code >= http.StatusInternalServerError {
+		log.Printf("backend error: %v", err)
+		return code, ErrInternal
+	}
+
+	return code, errors.New(s.Message())
+}
+
+// errorCode returns the stable code of err as seen on the current route.
+func errorCode(c *gin.Context, httpStatus int, err error) string {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+
+	resource := "gateway"
+	segments := strings.Split(strings.TrimPrefix(c.FullPath(), "/"), "/")
+	if len(segments) > 1 {
+		if name, ok := resourceNames[segments[1]]; ok {
+			resource = name
+		}
+	}
+
+	reason, ok := statusReasons[httpStatus]
+	if !ok {
+		reason = "error"
+	}
+	return resource + "." + reason
+}
+
+func problemDetails(c *gin.Context, httpStatus int, code, detail string, fields []models.FieldError) *models.ProblemDetails {
+	title := http.StatusText(httpStatus)
+	if httpStatus == statusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	return &models.ProblemDetails{
+		Type:     problemTypeBase + code,
+		Title:    title,
+		Status:   httpStatus,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		Errors:   fields,
+	}
+}
+
+// writeError is the single place error responses are written from, so
+// every handler and middleware reports failures in the same shape.
+// Clients asking for application/problem+json get an RFC 7807 body,
+// everyone else keeps getting models.ErrorResponse. The gateway's own
+// messages are translated to the language picked by Localize.
+func writeError(c *gin.Context, httpStatus int, err error) {
+	l := localizer(c)
+
+	fields := fieldErrors(l, err)
+	if len(fields) > 0 {
+		err = &validationError{fields: fields}
+	}
+
+	code := errorCode(c, httpStatus, err)
+	message := l.Translate(code, err.Error(), nil)
+	c.Header("Content-Language", l.Language())
+
+	if c.NegotiateFormat(binding.MIMEJSON, mimeProblemJSON) == mimeProblemJSON {
+		c.Header("Content-Type", mimeProblemJSON)
+		c.AbortWithStatusJSON(httpStatus, problemDetails(c, httpStatus, code, message, fields))
+		return
+	}
+
+	c.AbortWithStatusJSON(httpStatus, models.ErrorResponse{
+		Error:   message,
+		Details: fields,
+	})
+}
+
+// retryDelay returns the delay a google.rpc.RetryInfo detail of err asks
+// clients to wait before trying again.
+func retryDelay(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// ceilSeconds formats d as the whole seconds headers such as Retry-After
+// take, rounding up so clients never come back too early.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// handleGrpcError writes the response for an error returned by a backend rpc.
+func handleGrpcError(c *gin.Context, err error) {
+	delay, hasDelay := retryDelay(err)
+
+	code, err := grpcError(err)
+	if hasDelay && (code == http.StatusServiceUnavailable || code == http.StatusTooManyRequests) {
+		c.Header("Retry-After", ceilSeconds(delay))
+	}
+	writeError(c, code, err)
+}
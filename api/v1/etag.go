@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bufferedWriter holds back the response until the handler is done, so
+// that it can still be replaced by a 304.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// ETag tags successful responses of the route with a strong ETag derived
+// from their body and answers requests whose If-None-Match still matches
+// with 304 Not Modified.
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.status == http.StatusOK {
+			tag := bodyETag(w.body.Bytes())
+			c.Header("ETag", tag)
+
+			if etagMatches(c.GetHeader("If-None-Match"), tag, true) {
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+
+		c.Writer.WriteHeader(w.status)
+		if w.body.Len() == 0 {
+			c.Writer.WriteHeaderNow()
+			return
+		}
+		c.Writer.Write(w.body.Bytes())
+	}
+}
+
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// representationETag returns the ETag the ETag middleware gives v when a
+// handler sends it with c.JSON.
+func representationETag(v interface{}) (string, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return bodyETag(body), nil
+}
+
+// etagMatches reports whether header, an If-Match or If-None-Match list,
+// names tag. If-None-Match compares weakly, ignoring W/ prefixes.
+func etagMatches(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// setETag tags the response with the ETag of v, the representation being
+// sent, so the client can make its next update conditional on it.
+func setETag(c *gin.Context, v interface{}) {
+	if tag, err := representationETag(v); err == nil {
+		c.Header("ETag", tag)
+	}
+}
+
+// checkIfMatch rejects an update or delete with 412 when the request
+// carries an If-Match that doesn't name the current representation of the
+// resource, i.e. the client would overwrite changes it hasn't seen. The
+// check and the change are two rpcs, so it narrows the window for lost updates but
+// cannot close it.
+func checkIfMatch(c *gin.Context, current interface{}) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	tag, err := representationETag(current)
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, ErrInternal)
+		return false
+	}
+	if !etagMatches(header, tag, false) {
+		writeError(c, http.StatusPreconditionFailed, ErrPreconditionFailed)
+		return false
+	}
+	return true
+}
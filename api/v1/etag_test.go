@@ -0,0 +1,138 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+)
+
+// etagResources are the resources whose reads carry ETags and whose
+// updates and deletes honour If-Match.
+var etagResources = []struct {
+	path       string
+	updateBody string
+}{
+	{"/v1/posts/1", `{"title":"edited"}`},
+	{"/v1/comments/1", `{"description":"edited"}`},
+	{"/v1/users/1", `{"first_name":"Edited","last_name":"User","gender":"male"}`},
+}
+
+func newETagRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	client := newFakeClient()
+	client.users[1] = &pbu.User{Id: 1, FirstName: "Some", LastName: "User", Type: "user"}
+	client.posts[1] = &pbp.Post{Id: 1, UserId: 1, Title: "title"}
+	client.comments[1] = &pbp.Comment{Id: 1, PostId: 1, UserId: 1, Description: "comment"}
+	return newRouter(t, config.Config{}, client)
+}
+
+// currentETag fetches path and returns its ETag.
+func currentETag(t *testing.T, router http.Handler, path string) string {
+	t.Helper()
+
+	w := serve(router, http.MethodGet, path, "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d, want %d", path, w.Code, http.StatusOK)
+	}
+	tag := w.Header().Get("ETag")
+	if tag == "" {
+		t.Fatalf("GET %s: no ETag", path)
+	}
+	return tag
+}
+
+func TestETagIfNoneMatch(t *testing.T) {
+	router := newETagRouter(t)
+
+	for _, r := range etagResources {
+		tag := currentETag(t, router, r.path)
+
+		tests := []struct {
+			ifNoneMatch string
+			want        int
+		}{
+			{tag, http.StatusNotModified},
+			// If-None-Match compares weakly.
+			{"W/" + tag, http.StatusNotModified},
+			{`"stale", ` + tag, http.StatusNotModified},
+			{"*", http.StatusNotModified},
+			{`"stale"`, http.StatusOK},
+		}
+		for _, tt := range tests {
+			w := serve(router, http.MethodGet, r.path, "", http.Header{"If-None-Match": {tt.ifNoneMatch}})
+			if w.Code != tt.want {
+				t.Errorf("GET %s with If-None-Match %s: status = %d, want %d", r.path, tt.ifNoneMatch, w.Code, tt.want)
+				continue
+			}
+			if got := w.Header().Get("ETag"); got != tag {
+				t.Errorf("GET %s with If-None-Match %s: ETag = %q, want %q", r.path, tt.ifNoneMatch, got, tag)
+			}
+			if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("GET %s with If-None-Match %s: 304 with body %q", r.path, tt.ifNoneMatch, w.Body)
+			}
+		}
+	}
+}
+
+func TestETagIfMatch(t *testing.T) {
+	for _, r := range etagResources {
+		for _, method := range []string{http.MethodPut, http.MethodDelete} {
+			router := newETagRouter(t)
+			tag := currentETag(t, router, r.path)
+
+			body := ""
+			if method == http.MethodPut {
+				body = r.updateBody
+			}
+			send := func(ifMatch string) *httptest.ResponseRecorder {
+				header := authHeader(t, 1, "user")
+				header.Set("If-Match", ifMatch)
+				return serve(router, method, r.path, body, header)
+			}
+
+			// If-Match compares strongly.
+			for _, ifMatch := range []string{`"stale"`, "W/" + tag} {
+				w := send(ifMatch)
+				if w.Code != http.StatusPreconditionFailed {
+					t.Errorf("%s %s with If-Match %s: status = %d, want %d", method, r.path, ifMatch, w.Code, http.StatusPreconditionFailed)
+					continue
+				}
+
+				var problem models.ProblemDetails
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatal(err)
+				}
+				if problem.Code != "gateway.precondition_failed" {
+					t.Errorf("%s %s with If-Match %s: code = %q, want %q", method, r.path, ifMatch, problem.Code, "gateway.precondition_failed")
+				}
+			}
+			if got := currentETag(t, router, r.path); got != tag {
+				t.Fatalf("%s %s: rejected requests changed the resource", method, r.path)
+			}
+
+			if w := send(tag); w.Code >= 300 {
+				t.Errorf("%s %s with the current ETag: status = %d: %s", method, r.path, w.Code, w.Body)
+			}
+		}
+	}
+}
+
+func TestETagIfMatchAny(t *testing.T) {
+	for _, r := range etagResources {
+		router := newETagRouter(t)
+
+		header := authHeader(t, 1, "user")
+		header.Set("If-Match", "*")
+		if w := serve(router, http.MethodDelete, r.path, "", header); w.Code != http.StatusOK {
+			t.Errorf("DELETE %s with If-Match *: status = %d, want %d", r.path, w.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,548 @@
+package v1_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/api"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+	grpcPkg "github.com/samandar2605/medium_api_gateway/pkg/grpc_client"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+	"github.com/samandar2605/medium_api_gateway/pkg/policy"
+	"github.com/samandar2605/medium_api_gateway/pkg/ratelimit"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeClient stands in for the backend services with data kept in memory.
+// Rpcs it doesn't implement panic through the embedded nil interfaces.
+type fakeClient struct {
+	grpcPkg.GrpcClientI
+
+	// latency is how long every rpc takes to answer.
+	latency time.Duration
+	// rpcs counts the rpcs made.
+	rpcs atomic.Int64
+
+	mu         sync.Mutex
+	users      map[int64]*pbu.User
+	posts      map[int64]*pbp.Post
+	comments   map[int64]*pbp.Comment
+	categories map[int64]*pbp.Category
+	// likes holds the status of every like, by user and post.
+	likes map[[2]int64]bool
+	// sessions holds who the access tokens user_service verifies belong
+	// to.
+	sessions map[string]*pbu.AuthPayload
+	// passwords holds the password of every email that can log in.
+	passwords map[string]string
+	// userErr, when set, is returned by the next UserService.Get.
+	userErr error
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		users:      make(map[int64]*pbu.User),
+		posts:      make(map[int64]*pbp.Post),
+		comments:   make(map[int64]*pbp.Comment),
+		categories: make(map[int64]*pbp.Category),
+		likes:      make(map[[2]int64]bool),
+		sessions:   make(map[string]*pbu.AuthPayload),
+		passwords:  make(map[string]string),
+	}
+}
+
+func (f *fakeClient) AuthService() pbu.AuthServiceClient         { return fakeAuthService{f: f} }
+func (f *fakeClient) UserService() pbu.UserServiceClient         { return fakeUserService{f: f} }
+func (f *fakeClient) PostService() pbp.PostServiceClient         { return fakePostService{f: f} }
+func (f *fakeClient) CommentService() pbp.CommentServiceClient   { return fakeCommentService{f: f} }
+func (f *fakeClient) LikeService() pbp.LikeServiceClient         { return fakeLikeService{f: f} }
+func (f *fakeClient) CategoryService() pbp.CategoryServiceClient { return fakeCategoryService{f: f} }
+func (f *fakeClient) BreakerStatus() []grpcPkg.BreakerStatus     { return nil }
+
+// call waits for the rpc to be answered and fails the way a grpc client
+// does when ctx is done first.
+func (f *fakeClient) call(ctx context.Context) error {
+	f.rpcs.Add(1)
+	if f.latency > 0 {
+		timer := time.NewTimer(f.latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return nil
+}
+
+type fakeAuthService struct {
+	pbu.AuthServiceClient
+	f *fakeClient
+}
+
+// VerifyToken lets the owners of known tokens do anything.
+func (s fakeAuthService) VerifyToken(ctx context.Context, in *pbu.VerifyTokenRequest, opts ...grpc.CallOption) (*pbu.AuthPayload, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	payload, ok := s.f.sessions[strings.TrimPrefix(in.AccessToken, "Bearer ")]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	payload = proto.Clone(payload).(*pbu.AuthPayload)
+	payload.HasPermission = true
+	return payload, nil
+}
+
+func (s fakeAuthService) Login(ctx context.Context, in *pbu.VerifyRequest, opts ...grpc.CallOption) (*pbu.AuthResponse, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	password, ok := s.f.passwords[in.Email]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if password != in.Code {
+		return nil, status.Error(codes.InvalidArgument, "incorrect_password")
+	}
+	response := &pbu.AuthResponse{Email: in.Email, Type: "user", AccessToken: "access-token"}
+	for _, user := range s.f.users {
+		if user.Email == in.Email {
+			response.Id, response.Type = user.Id, user.Type
+		}
+	}
+	return response, nil
+}
+
+type fakeUserService struct {
+	pbu.UserServiceClient
+	f *fakeClient
+}
+
+func (s fakeUserService) Get(ctx context.Context, in *pbu.IdRequest, opts ...grpc.CallOption) (*pbu.User, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	if err := s.f.userErr; err != nil {
+		s.f.userErr = nil
+		return nil, err
+	}
+	user, ok := s.f.users[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return proto.Clone(user).(*pbu.User), nil
+}
+
+func (s fakeUserService) Update(ctx context.Context, in *pbu.UpdateUser, opts ...grpc.CallOption) (*pbu.User, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	user, ok := s.f.users[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	user.FirstName = in.FirstName
+	user.LastName = in.LastName
+	return proto.Clone(user).(*pbu.User), nil
+}
+
+func (s fakeUserService) Delete(ctx context.Context, in *pbu.DeleteUserRequest, opts ...grpc.CallOption) (*pbu.Empty, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	if _, ok := s.f.users[in.Id]; !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	delete(s.f.users, in.Id)
+	return &pbu.Empty{}, nil
+}
+
+type fakePostService struct {
+	pbp.PostServiceClient
+	f *fakeClient
+}
+
+func (s fakePostService) Get(ctx context.Context, in *pbp.GetPostRequest, opts ...grpc.CallOption) (*pbp.Post, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	post, ok := s.f.posts[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	return proto.Clone(post).(*pbp.Post), nil
+}
+
+func (s fakePostService) GetAll(ctx context.Context, in *pbp.GetAllPostsRequest, opts ...grpc.CallOption) (*pbp.GetAllPostsResponse, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	result := &pbp.GetAllPostsResponse{}
+	for _, post := range s.f.posts {
+		result.Count++
+		if int64(len(result.Posts)) < int64(in.Limit) {
+			result.Posts = append(result.Posts, proto.Clone(post).(*pbp.Post))
+		}
+	}
+	return result, nil
+}
+
+func (s fakePostService) Create(ctx context.Context, in *pbp.CreatePost, opts ...grpc.CallOption) (*pbp.Post, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	post := &pbp.Post{
+		Id:          int64(len(s.f.posts) + 1),
+		Title:       in.Title,
+		Description: in.Description,
+		UserId:      in.UserId,
+		CategoryId:  in.CategoryId,
+	}
+	s.f.posts[post.Id] = post
+	return proto.Clone(post).(*pbp.Post), nil
+}
+
+func (s fakePostService) Update(ctx context.Context, in *pbp.ChangePost, opts ...grpc.CallOption) (*pbp.Post, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	post, ok := s.f.posts[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	post.Title = in.Title
+	post.Description = in.Description
+	post.UserId = in.UserId
+	return proto.Clone(post).(*pbp.Post), nil
+}
+
+func (s fakePostService) Delete(ctx context.Context, in *pbp.GetPostRequest, opts ...grpc.CallOption) (*pbp.Blank, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	if _, ok := s.f.posts[in.Id]; !ok {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	delete(s.f.posts, in.Id)
+	return &pbp.Blank{}, nil
+}
+
+type fakeCommentService struct {
+	pbp.CommentServiceClient
+	f *fakeClient
+}
+
+func (s fakeCommentService) Create(ctx context.Context, in *pbp.CreateCommentRequest, opts ...grpc.CallOption) (*pbp.Comment, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	comment := &pbp.Comment{
+		Id:          int64(len(s.f.comments) + 1),
+		PostId:      in.PostId,
+		UserId:      in.UserId,
+		Description: in.Description,
+	}
+	s.f.comments[comment.Id] = comment
+	return proto.Clone(comment).(*pbp.Comment), nil
+}
+
+func (s fakeCommentService) Get(ctx context.Context, in *pbp.IdWithRequest, opts ...grpc.CallOption) (*pbp.Comment, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	comment, ok := s.f.comments[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "comment not found")
+	}
+	return proto.Clone(comment).(*pbp.Comment), nil
+}
+
+func (s fakeCommentService) GetAll(ctx context.Context, in *pbp.GetCommentQuery, opts ...grpc.CallOption) (*pbp.GetAllCommentsResult, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	result := &pbp.GetAllCommentsResult{}
+	for _, comment := range s.f.comments {
+		if in.PostId != 0 && comment.PostId != in.PostId {
+			continue
+		}
+		result.Count++
+		if int64(len(result.Comments)) < in.Limit {
+			result.Comments = append(result.Comments, proto.Clone(comment).(*pbp.Comment))
+		}
+	}
+	return result, nil
+}
+
+func (s fakeCommentService) Update(ctx context.Context, in *pbp.Comment, opts ...grpc.CallOption) (*pbp.Comment, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	comment, ok := s.f.comments[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "comment not found")
+	}
+	comment.Description = in.Description
+	comment.UserId = in.UserId
+	return proto.Clone(comment).(*pbp.Comment), nil
+}
+
+func (s fakeCommentService) Delete(ctx context.Context, in *pbp.IdWithRequest, opts ...grpc.CallOption) (*pbp.Boosh, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	if _, ok := s.f.comments[in.Id]; !ok {
+		return nil, status.Error(codes.NotFound, "comment not found")
+	}
+	delete(s.f.comments, in.Id)
+	return &pbp.Boosh{}, nil
+}
+
+type fakeLikeService struct {
+	pbp.LikeServiceClient
+	f *fakeClient
+}
+
+func (s fakeLikeService) CreateOrUpdate(ctx context.Context, in *pbp.CreateOrUpdateLikeRequest, opts ...grpc.CallOption) (*pbp.Pustoy, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	s.f.likes[[2]int64{in.UserId, in.PostId}] = in.Status
+	return &pbp.Pustoy{}, nil
+}
+
+func (s fakeLikeService) Get(ctx context.Context, in *pbp.GetLike, opts ...grpc.CallOption) (*pbp.CreateOrUpdateLikeRequest, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	liked, ok := s.f.likes[[2]int64{in.UserId, in.PostId}]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "like not found")
+	}
+	return &pbp.CreateOrUpdateLikeRequest{UserId: in.UserId, PostId: in.PostId, Status: liked}, nil
+}
+
+func (s fakeLikeService) GetLikesDislikesCount(ctx context.Context, in *pbp.GetAllRequest, opts ...grpc.CallOption) (*pbp.GetAllResponse, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	result := &pbp.GetAllResponse{}
+	for key, liked := range s.f.likes {
+		switch {
+		case key[1] != in.PostId:
+		case liked:
+			result.LikesCount++
+		default:
+			result.DislikesCount++
+		}
+	}
+	return result, nil
+}
+
+type fakeCategoryService struct {
+	pbp.CategoryServiceClient
+	f *fakeClient
+}
+
+func (s fakeCategoryService) Get(ctx context.Context, in *pbp.IdByRequest, opts ...grpc.CallOption) (*pbp.Category, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	category, ok := s.f.categories[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "category not found")
+	}
+	return proto.Clone(category).(*pbp.Category), nil
+}
+
+func (s fakeCategoryService) Update(ctx context.Context, in *pbp.Category, opts ...grpc.CallOption) (*pbp.Category, error) {
+	if err := s.f.call(ctx); err != nil {
+		return nil, err
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	category, ok := s.f.categories[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "category not found")
+	}
+	category.Title = in.Title
+	return proto.Clone(category).(*pbp.Category), nil
+}
+
+// testSecretKey signs the access tokens of the tests, which the router
+// verifies locally.
+const testSecretKey = "0123456789abcdef0123456789abcdef"
+
+// newRouter builds the gateway's router in front of client.
+func newRouter(t *testing.T, cfg config.Config, client grpcPkg.GrpcClientI) *gin.Engine {
+	t.Helper()
+	return newRouterWith(t, api.RouterOptions{Cfg: &cfg, GrpcClient: client})
+}
+
+// newRouterWith builds the gateway's router from opt, filling in what the
+// tests don't care about.
+func newRouterWith(t *testing.T, opt api.RouterOptions) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	if opt.Cfg.AuthVerifyMode == "" {
+		opt.Cfg.AuthVerifyMode = config.AuthVerifyLocal
+	}
+
+	var err error
+	if opt.Tokens == nil {
+		if opt.Tokens, err = token.NewMaker(testSecretKey, token.DefaultClaimLayout); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opt.Catalog == nil {
+		if opt.Catalog, err = i18n.Load(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opt.Policy == nil {
+		if opt.Policy, err = policy.Load(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opt.RateLimiter == nil {
+		opt.RateLimiter = ratelimit.NewMemory()
+	}
+
+	router, err := api.New(&opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+// authHeader returns the Authorization header of a user of userType.
+func authHeader(t *testing.T, userID int64, userType string) http.Header {
+	t.Helper()
+
+	tokens, err := token.NewMaker(testSecretKey, token.DefaultClaimLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken, _, err := tokens.Create(userID, "user@example.com", userType, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return http.Header{"Authorization": {"Bearer " + accessToken}}
+}
+
+// serve sends a request to router and records the response. Errors are
+// asked for as problem+json so their codes can be checked.
+func serve(router http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	return serveFrom(router, "192.0.2.1:1234", method, path, body, header)
+}
+
+// serveFrom is serve for a request the gateway receives from remoteAddr.
+func serveFrom(router http.Handler, remoteAddr, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/problem+json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -2,44 +2,107 @@ package v1
 
 import (
 	"errors"
+	"io"
+	"log"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/samandar2605/medium_api_gateway/api/models"
 	"github.com/samandar2605/medium_api_gateway/config"
+	"github.com/samandar2605/medium_api_gateway/pkg/cache"
 	grpcPkg "github.com/samandar2605/medium_api_gateway/pkg/grpc_client"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+	"github.com/samandar2605/medium_api_gateway/pkg/policy"
+	"github.com/samandar2605/medium_api_gateway/pkg/ratelimit"
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
 )
 
 var (
-	ErrWrongEmailOrPass = errors.New("wrong email or password")
-	ErrEmailExists      = errors.New("email already exists")
-	ErrUserNotVerified  = errors.New("user not verified")
-	ErrIncorrectCode    = errors.New("incorrect verification code")
-	ErrCodeExpired      = errors.New("verification code has been expired")
-	ErrNotAllowed       = errors.New("method not allowed")
-	ErrForbidden        = errors.New("forbidden")
+	ErrWrongEmailOrPass   = errors.New("wrong email or password")
+	ErrEmailExists        = errors.New("email already exists")
+	ErrUserNotVerified    = errors.New("user not verified")
+	ErrIncorrectCode      = errors.New("incorrect verification code")
+	ErrCodeExpired        = errors.New("verification code has been expired")
+	ErrNotAllowed         = errors.New("method not allowed")
+	ErrForbidden          = errors.New("forbidden")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrMissingToken       = errors.New("authorization header is not provided")
+	ErrRequestTimeout     = errors.New("request timed out waiting for the backend service")
+	ErrRequestCanceled    = errors.New("request canceled by the client")
+	ErrServiceUnavailable = errors.New("service is temporarily unavailable")
+	ErrInternal           = errors.New("internal server error")
+
+	ErrTokenRevoked         = errors.New("token has been revoked")
+	ErrInvalidRefreshToken  = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused   = errors.New("refresh token has already been used, log in again")
+	ErrRefreshNotConfigured = errors.New("refresh tokens are not enabled on this gateway")
+	ErrLogoutNotConfigured  = errors.New("logging out is not enabled on this gateway")
+
+	ErrTooManyAttempts = errors.New("too many attempts, try again later")
+	ErrRateLimited     = errors.New("rate limit exceeded, slow down")
+
+	ErrPreconditionFailed = errors.New("resource has changed since it was fetched")
+)
+
+// User types known to the gateway.
+const (
+	userTypeUser       = "user"
+	userTypeSuperadmin = "superadmin"
 )
 
 type handlerV1 struct {
 	cfg        *config.Config
 	grpcClient grpcPkg.GrpcClientI
+	catalog    *i18n.Catalog
+	tokens     *token.Maker
+	policy     *policy.Policy
+	verified   *verifyCache
+	auditLog   *log.Logger
+	store      store.Store
+	limiter    ratelimit.Limiter
+	responses  cache.Cache
 }
 
 type HandlerV1Options struct {
 	Cfg        *config.Config
 	GrpcClient *grpcPkg.GrpcClientI
+	Catalog    *i18n.Catalog
+	// Tokens is set when AUTH_SECRET_KEY is configured. It verifies tokens
+	// in local mode and issues them on refresh.
+	Tokens *token.Maker
+	Policy *policy.Policy
+	// Store keeps refresh tokens, revoked tokens and failed attempts.
+	Store store.Store
+	// RateLimiter keeps the buckets of RateLimit; without it nothing is
+	// limited.
+	RateLimiter ratelimit.Limiter
+	// ResponseCache keeps the responses of CacheResponse; without it
+	// nothing is cached.
+	ResponseCache cache.Cache
+	// AuditLog receives a JSON line for every privileged change.
+	AuditLog io.Writer
 }
 
 func New(options *HandlerV1Options) *handlerV1 {
+	registerJSONFieldNames()
+
+	var auditLog *log.Logger
+	if options.AuditLog != nil {
+		auditLog = log.New(options.AuditLog, "", 0)
+	}
+
 	return &handlerV1{
 		cfg:        options.Cfg,
 		grpcClient: *options.GrpcClient,
-	}
-}
-
-func errorResponse(err error) *models.ErrorResponse {
-	return &models.ErrorResponse{
-		Error: err.Error(),
+		catalog:    options.Catalog,
+		tokens:     options.Tokens,
+		policy:     options.Policy,
+		verified:   newVerifyCache(options.Cfg.AuthCacheSize, options.Cfg.AuthCacheTTL),
+		auditLog:   auditLog,
+		store:      options.Store,
+		limiter:    options.RateLimiter,
+		responses:  options.ResponseCache,
 	}
 }
 
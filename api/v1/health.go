@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+)
+
+const (
+	healthStatusUp   = "up"
+	healthStatusDown = "down"
+)
+
+// Healthz tells the orchestrator the process is alive. It never looks at
+// the backends, a restart would not fix them.
+func (h *handlerV1) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{
+		Status: healthStatusUp,
+	})
+}
+
+// Readyz reports whether the gateway can serve traffic, which it can only
+// do while every backend service is reachable and serving.
+func (h *handlerV1) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.HealthCheckTimeout)
+	defer cancel()
+
+	response := models.HealthResponse{
+		Status: healthStatusUp,
+	}
+	code := http.StatusOK
+
+	for _, backend := range h.grpcClient.CheckHealth(ctx) {
+		dependency := models.DependencyHealth{
+			Name:   backend.Name,
+			Status: healthStatusUp,
+			State:  backend.State.String(),
+		}
+		if backend.Err != nil {
+			dependency.Error = backend.Err.Error()
+		}
+		if !backend.Serving {
+			dependency.Status = healthStatusDown
+			response.Status = healthStatusDown
+			code = http.StatusServiceUnavailable
+		}
+
+		response.Dependencies = append(response.Dependencies, dependency)
+	}
+
+	c.JSON(code, response)
+}
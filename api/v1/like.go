@@ -1,7 +1,6 @@
 package v1
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 
@@ -27,23 +26,23 @@ func (h *handlerV1) CreateOrUpdateLike(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	payload, err := h.GetAuthPayload(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, http.StatusUnauthorized, err)
 		return
 	}
 
-	_, err = h.grpcClient.LikeService().CreateOrUpdate(context.Background(), &pb.CreateOrUpdateLikeRequest{
+	_, err = h.grpcClient.LikeService().CreateOrUpdate(c.Request.Context(), &pb.CreateOrUpdateLikeRequest{
 		UserId: payload.UserID,
 		PostId: int64(req.PostId),
 		Status: req.Status,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -65,22 +64,23 @@ func (h *handlerV1) CreateOrUpdateLike(c *gin.Context) {
 func (h *handlerV1) GetLike(c *gin.Context) {
 	PostId, err := strconv.Atoi(c.Query("post_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	payload, err := h.GetAuthPayload(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, http.StatusUnauthorized, err)
 		return
 	}
 
-	resp, err := h.grpcClient.LikeService().Get(context.Background(), &pb.GetLike{
+	resp, err := h.grpcClient.LikeService().Get(c.Request.Context(), &pb.GetLike{
 		UserId: payload.UserID,
 		PostId: int64(PostId),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
+		return
 	}
 	c.JSON(http.StatusOK, models.Like{
 		Id:     int(resp.Id),
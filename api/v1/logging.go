@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	requestIDHeader   = "X-Request-ID"
+	requestIDKey      = "request_id"
+	requestIDMetadata = "x-request-id"
+
+	maxRequestIDLength = 128
+)
+
+// RequestID makes sure every request carries an id. An id sent by the
+// client is kept when it looks sane, otherwise a new one is generated. The
+// id is echoed in the response and forwarded to the backends as gRPC
+// metadata so their logs can be correlated with the gateway's.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(
+			metadata.AppendToOutgoingContext(c.Request.Context(), requestIDMetadata, id),
+		)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+type accessLogEntry struct {
+	Time      string  `json:"time"`
+	RequestID string  `json:"request_id"`
+	TraceID   string  `json:"trace_id,omitempty"`
+	Method    string  `json:"method"`
+	Route     string  `json:"route"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Bytes     int     `json:"bytes"`
+	ClientIP  string  `json:"client_ip"`
+	UserID    int64   `json:"user_id,omitempty"`
+	Errors    string  `json:"errors,omitempty"`
+}
+
+// AccessLog writes one JSON line per request to out. The route is logged
+// as its template (/v1/posts/:id) so lines can be grouped per endpoint.
+func AccessLog(out io.Writer) gin.HandlerFunc {
+	logger := log.New(out, "", 0)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := accessLogEntry{
+			Time:      start.UTC().Format(time.RFC3339Nano),
+			RequestID: c.GetString(requestIDKey),
+			Method:    c.Request.Method,
+			Route:     c.FullPath(),
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:     c.Writer.Size(),
+			ClientIP:  c.ClientIP(),
+			Errors:    c.Errors.String(),
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			entry.TraceID = sc.TraceID().String()
+		}
+		if entry.Bytes < 0 {
+			entry.Bytes = 0
+		}
+		if payload, ok := c.Value(authorizationPayloadKey).(Payload); ok {
+			entry.UserID = payload.UserID
+		}
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			logger.Printf(`{"error":"failed to encode access log: %v"}`, err)
+			return
+		}
+		logger.Println(string(line))
+	}
+}
@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+)
+
+// unmatchedRoute labels requests no route matched, so arbitrary paths do
+// not blow up the metrics' cardinality.
+const unmatchedRoute = "unmatched"
+
+// Metrics records request count, latency and in-flight requests per route
+// template.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+
+		inFlight := metrics.HTTPRequestsInFlight.WithLabelValues(route, method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		c.Next()
+
+		code := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(route, method, code).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route, method, code).Observe(time.Since(start).Seconds())
+	}
+}
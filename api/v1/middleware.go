@@ -3,16 +3,28 @@ package v1
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/config"
 	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+	grpcPkg "github.com/samandar2605/medium_api_gateway/pkg/grpc_client"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+	"github.com/samandar2605/medium_api_gateway/pkg/policy"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationPayloadKey = "authorization_payload"
+	// authorizationConditionKey holds the condition the policy attached
+	// to the request, if any.
+	authorizationConditionKey = "authorization_condition"
+	localizerKey              = "localizer"
 )
 
 type Payload struct {
@@ -24,47 +36,180 @@ type Payload struct {
 	ExpiredAt string `json:"expired_at"`
 }
 
+// Deadline bounds the lifetime of every request in a route group. The
+// deadline is carried by the request context, so it also applies to the
+// gRPC calls made while handling the request.
+func Deadline(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Coalesce lets the backend reads of the route share their result with
+// identical reads already in flight, so a burst of requests for the same
+// thing costs a single rpc.
+func Coalesce() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(grpcPkg.WithCoalescing(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// Localize picks the language of the request's error messages from its
+// Accept-Language header.
+func (h *handlerV1) Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.catalog != nil {
+			c.Set(localizerKey, h.catalog.Localizer(c.GetHeader("Accept-Language")))
+		}
+		c.Next()
+	}
+}
+
+func localizer(c *gin.Context) *i18n.Localizer {
+	l, _ := c.Value(localizerKey).(*i18n.Localizer)
+	return l
+}
+
 func (h *handlerV1) AuthMiddleware(resource, action string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		accessToken := c.GetHeader(authorizationHeaderKey)
-		fmt.Println(c.Request.URL.Path)
 		if len(accessToken) == 0 {
-			err := errors.New("authorization header is not provided")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(c, http.StatusUnauthorized, ErrMissingToken)
 			return
 		}
 
-		payload, err := h.grpcClient.AuthService().VerifyToken(context.Background(), &pbu.VerifyTokenRequest{
-			AccessToken: accessToken,
-			Resource:    resource,
-			Action:      action,
-		})
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		v, ok := h.authenticate(c, accessToken, resource, action)
+		if !ok {
 			return
 		}
 
-		if !payload.HasPermission {
-			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrNotAllowed))
+		if h.store != nil {
+			revoked, err := h.isRevoked(c.Request.Context(), v.payload)
+			if err != nil {
+				log.Printf("failed to check token revocation: %v", err)
+				writeError(c, http.StatusServiceUnavailable, ErrServiceUnavailable)
+				return
+			}
+			if revoked {
+				writeError(c, http.StatusUnauthorized, ErrTokenRevoked)
+				return
+			}
+		}
+
+		decision := h.policy.Decide(v.payload.UserType, resource, action)
+		if !decision.Known {
+			// The policy doesn't cover this user type, so user_service
+			// decides, and only for things the user owns.
+			if !v.remote && h.cfg.AuthRemoteFallback {
+				if v, ok = h.verifyRemotely(c, accessToken, resource, action); !ok {
+					return
+				}
+			}
+			decision.Allowed = v.remote && v.hasPermission
+			decision.Condition = policy.ConditionOwner
+		}
+
+		if !decision.Allowed {
+			writeError(c, http.StatusForbidden, ErrNotAllowed)
+			return
+		}
+
+		if !h.limitUser(c, v.payload) {
 			return
 		}
 
-		c.Set(authorizationPayloadKey, Payload{
+		c.Set(authorizationPayloadKey, v.payload)
+		c.Set(authorizationConditionKey, decision.Condition)
+		c.Next()
+	}
+}
+
+// authenticate finds out who sent the request, locally in the local verify
+// mode and through user_service otherwise. On failure
+// the response has already been written.
+func (h *handlerV1) authenticate(c *gin.Context, accessToken, resource, action string) (verification, bool) {
+	if h.tokens == nil || h.cfg.AuthVerifyMode != config.AuthVerifyLocal {
+		return h.verifyRemotely(c, accessToken, resource, action)
+	}
+
+	claims, err := h.tokens.Verify(strings.TrimPrefix(accessToken, "Bearer "))
+	switch {
+	case err == nil:
+	case errors.Is(err, token.ErrInvalidToken) && h.cfg.AuthRemoteFallback:
+		// Possibly signed with a key the gateway doesn't know (yet).
+		return h.verifyRemotely(c, accessToken, resource, action)
+	default:
+		writeError(c, http.StatusUnauthorized, err)
+		return verification{}, false
+	}
+
+	return verification{
+		payload: Payload{
+			ID:        claims.ID,
+			UserID:    claims.UserID,
+			Email:     claims.Email,
+			UserType:  claims.UserType,
+			IssuedAt:  claims.IssuedAt.Format(time.RFC3339Nano),
+			ExpiredAt: claims.ExpiredAt.Format(time.RFC3339),
+		},
+	}, true
+}
+
+// verifyRemotely asks user_service to verify the token and whether its
+// owner may perform action on resource.
+func (h *handlerV1) verifyRemotely(c *gin.Context, accessToken, resource, action string) (verification, bool) {
+	key := verifyCacheKey(accessToken, resource, action)
+	if v, ok := h.verified.get(key); ok {
+		return v, true
+	}
+
+	payload, err := h.grpcClient.AuthService().VerifyToken(c.Request.Context(), &pbu.VerifyTokenRequest{
+		AccessToken: accessToken,
+		Resource:    resource,
+		Action:      action,
+	})
+	if err != nil {
+		// Backend outages are reported as such; anything else means
+		// the token itself was rejected.
+		if code, _ := grpcError(err); code >= http.StatusInternalServerError {
+			handleGrpcError(c, err)
+			return verification{}, false
+		}
+		writeError(c, http.StatusUnauthorized, errors.New(status.Convert(err).Message()))
+		return verification{}, false
+	}
+
+	v := verification{
+		payload: Payload{
 			ID:        payload.Id,
 			UserID:    payload.UserId,
 			Email:     payload.Email,
 			UserType:  payload.UserType,
 			IssuedAt:  payload.IssuedAt,
 			ExpiredAt: payload.ExpiredAt,
-		})
-		c.Next()
+		},
+		remote:        true,
+		hasPermission: payload.HasPermission,
 	}
+	h.verified.set(key, v)
+	h.verified.set(ownerKey(accessToken), verification{payload: v.payload})
+	return v, true
 }
 
 func (m *handlerV1) GetAuthPayload(ctx *gin.Context) (*Payload, error) {
 	i, exists := ctx.Get(authorizationPayloadKey)
 	if !exists {
-		return nil, errors.New("")
+		return nil, ErrUnauthorized
 	}
 
 	payload, ok := i.(Payload)
@@ -0,0 +1,91 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+)
+
+func TestDeadline(t *testing.T) {
+	tests := []struct {
+		name       string
+		latency    time.Duration
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "backend answers in time", latency: 0, wantStatus: http.StatusOK},
+		{name: "backend too slow", latency: time.Second, wantStatus: http.StatusGatewayTimeout, wantCode: "gateway.timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient()
+			client.latency = tt.latency
+			client.posts[1] = &pbp.Post{Id: 1, Title: "title", UserId: 1}
+
+			router := newRouter(t, config.Config{PostTimeout: 50 * time.Millisecond}, client)
+
+			start := time.Now()
+			w := serve(router, http.MethodGet, "/v1/posts/1", "", nil)
+			if elapsed := time.Since(start); elapsed >= tt.latency && tt.latency > 0 {
+				t.Errorf("request took %s, the backend's full latency", elapsed)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+
+			var problem models.ProblemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestVerifyCacheFollowsTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiredAt string
+		cached    bool
+	}{
+		{"expires later", time.Now().Add(time.Hour).Format(time.RFC3339), true},
+		{"expires soon", time.Now().Add(time.Second).Format(time.RFC3339), true},
+		{"unknown expiry", time.Now().Add(time.Hour).Format(time.UnixDate), false},
+		{"no expiry", "", false},
+	}
+	for _, tt := range tests {
+		client := newFakeClient()
+		client.sessions["token"] = &pbu.AuthPayload{Id: "1", UserId: 1, UserType: "user", ExpiredAt: tt.expiredAt}
+		client.likes[[2]int64{1, 1}] = true
+		router := newRouter(t, config.Config{
+			AuthVerifyMode: config.AuthVerifyRemote,
+			AuthCacheSize:  100,
+			AuthCacheTTL:   time.Minute,
+		}, client)
+
+		header := http.Header{"Authorization": {"Bearer token"}}
+		countRPCs(router, client, http.MethodGet, "/v1/likes/user-post?post_id=1", "", header)
+		w, rpcs := countRPCs(router, client, http.MethodGet, "/v1/likes/user-post?post_id=1", "", header)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.name, w.Code, w.Body)
+		}
+		// One rpc fetches the like, another one verifies the token
+		// unless its verification was cached.
+		if cached := rpcs == 1; cached != tt.cached {
+			t.Errorf("%s: verification cached = %t, want %t", tt.name, cached, tt.cached)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	"github.com/samandar2605/medium_api_gateway/pkg/policy"
+)
+
+// ownerRequired reports whether the policy only let the current request
+// through for resources the user owns. Admins are typically not
+// restricted this way and may act on anyone's resources.
+func ownerRequired(c *gin.Context) bool {
+	return c.GetString(authorizationConditionKey) == policy.ConditionOwner
+}
+
+// authorizeOwner enforces the "owner" condition the policy may have put on
+// the current request: ownerID must be the authenticated user. It writes
+// the error response and returns false when the condition doesn't hold.
+func (h *handlerV1) authorizeOwner(c *gin.Context, ownerID int64) bool {
+	if !ownerRequired(c) {
+		return true
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		writeError(c, http.StatusUnauthorized, err)
+		return false
+	}
+	if payload.UserID != ownerID {
+		writeError(c, http.StatusForbidden, ErrForbidden)
+		return false
+	}
+	return true
+}
+
+// ownedPost fetches the post a mutation targets and makes sure the user
+// may change it. On failure the response has already been written.
+func (h *handlerV1) ownedPost(c *gin.Context, id int64) (*pbp.Post, bool) {
+	post, err := h.grpcClient.PostService().Get(c.Request.Context(), &pbp.GetPostRequest{Id: id})
+	if err != nil {
+		handleGrpcError(c, err)
+		return nil, false
+	}
+	if !h.authorizeOwner(c, post.UserId) {
+		return nil, false
+	}
+	return post, true
+}
+
+// ownedComment is ownedPost for comments.
+func (h *handlerV1) ownedComment(c *gin.Context, id int64) (*pbp.Comment, bool) {
+	comment, err := h.grpcClient.CommentService().Get(c.Request.Context(), &pbp.IdWithRequest{Id: id})
+	if err != nil {
+		handleGrpcError(c, err)
+		return nil, false
+	}
+	if !h.authorizeOwner(c, comment.UserId) {
+		return nil, false
+	}
+	return comment, true
+}
@@ -0,0 +1,282 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+)
+
+// The users acting in the ownership tests. ownerID owns the post, the
+// comment and the account the requests target.
+const (
+	ownerID      = 1
+	otherID      = 2
+	adminID      = 3
+	superadminID = 4
+
+	postID    = 10
+	commentID = 20
+	missingID = 99
+)
+
+func newOwnershipClient() *fakeClient {
+	client := newFakeClient()
+	client.users[ownerID] = &pbu.User{Id: ownerID, FirstName: "Owner", Type: "user"}
+	client.users[otherID] = &pbu.User{Id: otherID, FirstName: "Other", Type: "user"}
+	client.users[adminID] = &pbu.User{Id: adminID, FirstName: "Admin", Type: "admin"}
+	client.users[superadminID] = &pbu.User{Id: superadminID, FirstName: "Super", Type: "superadmin"}
+	client.posts[postID] = &pbp.Post{Id: postID, Title: "title", UserId: ownerID}
+	client.comments[commentID] = &pbp.Comment{Id: commentID, PostId: postID, UserId: ownerID, Description: "text"}
+	return client
+}
+
+type actor struct {
+	name     string
+	userID   int64
+	userType string
+}
+
+var (
+	owner      = actor{"owner", ownerID, "user"}
+	other      = actor{"other user", otherID, "user"}
+	admin      = actor{"admin", adminID, "admin"}
+	superadmin = actor{"superadmin", superadminID, "superadmin"}
+)
+
+type ownershipCase struct {
+	actor      actor
+	id         int64
+	wantStatus int
+	wantCode   string
+}
+
+// mutation is a mutating route that targets a resource owned by someone.
+type mutation struct {
+	method string
+	path   string
+	body   string
+	// changed reports whether the request went through to the backend.
+	changed func(client *fakeClient, id int64) bool
+	cases   []ownershipCase
+}
+
+// ownedResourceCases are the outcomes for posts and comments, where admins
+// may act on anyone's.
+func ownedResourceCases(okStatus int) []ownershipCase {
+	return []ownershipCase{
+		{actor: owner, id: postID, wantStatus: okStatus},
+		{actor: other, id: postID, wantStatus: http.StatusForbidden, wantCode: "auth.forbidden"},
+		{actor: admin, id: postID, wantStatus: okStatus},
+		{actor: superadmin, id: postID, wantStatus: okStatus},
+		{actor: owner, id: missingID, wantStatus: http.StatusNotFound},
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	postChanged := func(client *fakeClient, id int64) bool {
+		post, ok := client.posts[id]
+		return !ok || post.Title != "title"
+	}
+	commentChanged := func(client *fakeClient, id int64) bool {
+		comment, ok := client.comments[id]
+		return !ok || comment.Description != "text"
+	}
+	userChanged := func(client *fakeClient, id int64) bool {
+		user, ok := client.users[id]
+		return !ok || user.FirstName != "Owner"
+	}
+
+	commentCases := ownedResourceCases(http.StatusOK)
+	for i := range commentCases {
+		if commentCases[i].id == postID {
+			commentCases[i].id = commentID
+		}
+	}
+
+	// Accounts are only ever changed by their owner and superadmins; the
+	// default policy gives admins no say over other accounts.
+	userCases := []ownershipCase{
+		{actor: owner, id: ownerID, wantStatus: http.StatusOK},
+		{actor: other, id: ownerID, wantStatus: http.StatusForbidden, wantCode: "auth.forbidden"},
+		{actor: admin, id: ownerID, wantStatus: http.StatusForbidden, wantCode: "auth.forbidden"},
+		{actor: superadmin, id: ownerID, wantStatus: http.StatusOK},
+		{actor: superadmin, id: missingID, wantStatus: http.StatusNotFound},
+	}
+
+	mutations := []mutation{
+		{
+			method:  http.MethodPut,
+			path:    "/v1/posts/",
+			body:    `{"title":"changed"}`,
+			changed: postChanged,
+			cases:   ownedResourceCases(http.StatusCreated),
+		},
+		{
+			method:  http.MethodDelete,
+			path:    "/v1/posts/",
+			changed: postChanged,
+			cases:   ownedResourceCases(http.StatusOK),
+		},
+		{
+			method:  http.MethodPut,
+			path:    "/v1/comments/",
+			body:    `{"description":"changed"}`,
+			changed: commentChanged,
+			cases:   commentCases,
+		},
+		{
+			method:  http.MethodDelete,
+			path:    "/v1/comments/",
+			changed: commentChanged,
+			cases:   commentCases,
+		},
+		{
+			method:  http.MethodPut,
+			path:    "/v1/users/",
+			body:    `{"first_name":"Changed","last_name":"Name","gender":"male"}`,
+			changed: userChanged,
+			cases:   userCases,
+		},
+		{
+			method:  http.MethodDelete,
+			path:    "/v1/users/",
+			changed: userChanged,
+			cases:   userCases,
+		},
+	}
+
+	for _, m := range mutations {
+		for _, tc := range m.cases {
+			path := m.path + strconv.FormatInt(tc.id, 10)
+			t.Run(m.method+" "+path+" by "+tc.actor.name, func(t *testing.T) {
+				client := newOwnershipClient()
+				router := newRouter(t, config.Config{}, client)
+
+				w := serve(router, m.method, path, m.body, authHeader(t, tc.actor.userID, tc.actor.userType))
+				if w.Code != tc.wantStatus {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body)
+				}
+				if tc.wantCode != "" {
+					var problem models.ProblemDetails
+					if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+						t.Fatal(err)
+					}
+					if problem.Code != tc.wantCode {
+						t.Errorf("code = %q, want %q", problem.Code, tc.wantCode)
+					}
+				}
+
+				allowed := w.Code < http.StatusBadRequest
+				if changed := m.changed(client, tc.id); changed != allowed && tc.id != missingID {
+					t.Errorf("resource changed = %v, want %v", changed, allowed)
+				}
+			})
+		}
+	}
+}
+
+// TestCreatedByCaller checks that new posts, comments and likes always
+// belong to the authenticated user, whatever user_id the body claims.
+func TestCreatedByCaller(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		// owners returns who the backend stored as the owners of
+		// everything created apart from the fixtures.
+		owners func(client *fakeClient) []int64
+	}{
+		{
+			name:       "post",
+			path:       "/v1/posts",
+			body:       `{"title":"new","user_id":1}`,
+			wantStatus: http.StatusCreated,
+			owners: func(client *fakeClient) []int64 {
+				var users []int64
+				for id, post := range client.posts {
+					if id != postID {
+						users = append(users, post.UserId)
+					}
+				}
+				return users
+			},
+		},
+		{
+			name:       "comment",
+			path:       "/v1/comments",
+			body:       `{"post_id":10,"description":"new","user_id":1}`,
+			wantStatus: http.StatusCreated,
+			owners: func(client *fakeClient) []int64 {
+				var users []int64
+				for id, comment := range client.comments {
+					if id != commentID {
+						users = append(users, comment.UserId)
+					}
+				}
+				return users
+			},
+		},
+		{
+			name:       "like",
+			path:       "/v1/likes",
+			body:       `{"post_id":10,"status":true,"user_id":1}`,
+			wantStatus: http.StatusOK,
+			owners: func(client *fakeClient) []int64 {
+				var users []int64
+				for key := range client.likes {
+					users = append(users, key[0])
+				}
+				return users
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newOwnershipClient()
+			router := newRouter(t, config.Config{}, client)
+
+			w := serve(router, http.MethodPost, tt.path, tt.body, authHeader(t, otherID, "user"))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			owners := tt.owners(client)
+			if len(owners) != 1 || owners[0] != otherID {
+				t.Errorf("created for users %v, want [%d]", owners, otherID)
+			}
+		})
+	}
+}
+
+func TestLikesAreTheCallers(t *testing.T) {
+	client := newOwnershipClient()
+	client.likes[[2]int64{ownerID, postID}] = true
+	router := newRouter(t, config.Config{}, client)
+
+	w := serve(router, http.MethodGet, "/v1/likes/user-post?post_id=10", "", authHeader(t, ownerID, "user"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var like models.Like
+	if err := json.Unmarshal(w.Body.Bytes(), &like); err != nil {
+		t.Fatal(err)
+	}
+	if like.UserId != ownerID {
+		t.Errorf("got the like of user %d, want %d", like.UserId, ownerID)
+	}
+
+	// Someone else asking about the same post only ever sees their own
+	// like, which doesn't exist.
+	w = serve(router, http.MethodGet, "/v1/likes/user-post?post_id=10", "", authHeader(t, otherID, "user"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+	}
+}
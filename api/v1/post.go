@@ -1,15 +1,12 @@
 package v1
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/samandar2605/medium_api_gateway/api/models"
 	pb "github.com/samandar2605/medium_api_gateway/genproto/post_service"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func parsePostModel(post *pb.Post) models.Post {
@@ -33,20 +30,20 @@ func parsePostModel(post *pb.Post) models.Post {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-None-Match header string false "ETag of the copy the client has"
 // @Success 200 {object} models.Post
+// @Success 304 "Not Modified"
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) GetPost(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	resp, err := h.grpcClient.PostService().Get(context.Background(), &pb.GetPostRequest{Id: int64(id)})
+	resp, err := h.grpcClient.PostService().Get(c.Request.Context(), &pb.GetPostRequest{Id: int64(id)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
 	post := parsePostModel(resp)
@@ -69,17 +66,15 @@ func (h *handlerV1) CreatePost(c *gin.Context) {
 	)
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 	payload, err := h.GetAuthPayload(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusUnauthorized, err)
 		return
 	}
-	resp, err := h.grpcClient.PostService().Create(context.Background(), &pb.CreatePost{
+	resp, err := h.grpcClient.PostService().Create(c.Request.Context(), &pb.CreatePost{
 		Title:       req.Title,
 		Description: req.Description,
 		CategoryId:  req.CategoryID,
@@ -87,9 +82,10 @@ func (h *handlerV1) CreatePost(c *gin.Context) {
 		UserId:      payload.UserID,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
+	h.invalidateResponses(c, CacheNamespacePosts)
 
 	post := parsePostModel(resp)
 	c.JSON(http.StatusCreated, post)
@@ -107,13 +103,11 @@ func (h *handlerV1) CreatePost(c *gin.Context) {
 func (h *handlerV1) GetAllPost(c *gin.Context) {
 	req, err := postsParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.grpcClient.PostService().GetAll(context.Background(), &pb.GetAllPostsRequest{
+	result, err := h.grpcClient.PostService().GetAll(c.Request.Context(), &pb.GetAllPostsRequest{
 		Page:       req.Page,
 		Limit:      req.Limit,
 		CategoryId: int32(req.CategoryID),
@@ -121,9 +115,8 @@ func (h *handlerV1) GetAllPost(c *gin.Context) {
 		SortByDate: req.SortByData,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
+		return
 	}
 
 	var res models.GetAllPostsResponse
@@ -207,8 +200,10 @@ func postsParams(c *gin.Context) (*models.GetAllPostsParams, error) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the version the update is based on"
 // @Param post body models.ChangePost true "post"
 // @Success 201 {object} models.Post
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) UpdatePost(c *gin.Context) {
 	var (
@@ -216,48 +211,37 @@ func (h *handlerV1) UpdatePost(c *gin.Context) {
 	)
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// payload, err := h.GetAuthPayload(c)
-	// if err != nil {
-	// 	c.JSON(http.StatusInternalServerError, errorResponse(err))
-	// 	return
-	// }
-
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	payload, err := h.GetAuthPayload(c)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+	target, ok := h.ownedPost(c, int64(id))
+	if !ok || !checkIfMatch(c, parsePostModel(target)) {
 		return
 	}
 
-	resp, err := h.grpcClient.PostService().Update(context.Background(), &pb.ChangePost{
+	resp, err := h.grpcClient.PostService().Update(c.Request.Context(), &pb.ChangePost{
 		Id:          int64(id),
-		UserId:      payload.UserID,
+		UserId:      target.UserId,
 		Title:       req.Title,
 		Description: req.Description,
 		ImageUrl:    req.ImageUrl,
 	})
 
 	if err != nil {
-		if s, _ := status.FromError(err); s.Code() == codes.NotFound {
-			c.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
+	h.invalidateResponses(c, CacheNamespacePosts)
 
 	post := parsePostModel(resp)
+	setETag(c, post)
 	c.JSON(http.StatusCreated, post)
 }
 
@@ -268,27 +252,33 @@ func (h *handlerV1) UpdatePost(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the version to delete"
+// @Success 200 {object} models.ResponseOK
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /posts/{id} [delete]
 func (h *handlerV1) DeletePost(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to convert",
-		})
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	target, ok := h.ownedPost(ctx, int64(id))
+	if !ok || !checkIfMatch(ctx, parsePostModel(target)) {
 		return
 	}
 
-	_, err = h.grpcClient.PostService().Delete(context.Background(), &pb.GetPostRequest{
+	_, err = h.grpcClient.PostService().Delete(ctx.Request.Context(), &pb.GetPostRequest{
 		Id: int64(id),
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to Delete method",
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "successful delete method",
+	h.invalidateResponses(ctx, CacheNamespacePosts)
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "successful delete method",
 	})
 }
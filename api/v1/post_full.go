@@ -0,0 +1,185 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+)
+
+// Sections of models.PostFull.
+const (
+	sectionAuthor   = "author"
+	sectionCategory = "category"
+	sectionComments = "comments"
+	sectionLikes    = "likes"
+)
+
+const (
+	defaultFullPostComments = 10
+	maxFullPostComments     = 100
+)
+
+var errInvalidCommentsLimit = errors.New("comments_limit must be at least 1")
+
+// postSections collects the sections of a PostFull loaded concurrently.
+type postSections struct {
+	mu       sync.Mutex
+	response models.PostFull
+}
+
+func (s *postSections) fail(c *gin.Context, section string, err error) {
+	httpStatus, err := grpcError(err)
+	reason, ok := statusReasons[httpStatus]
+	if !ok {
+		reason = "error"
+	}
+	// The gateway's own errors keep their translations.
+	message := localizer(c).Translate(errorCode(c, httpStatus, err), err.Error(), nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.response.Errors == nil {
+		s.response.Errors = make(map[string]models.SectionError)
+	}
+	s.response.Errors[section] = models.SectionError{
+		Code:    section + "." + reason,
+		Message: message,
+	}
+}
+
+// @Router /posts/{id}/full [get]
+// @Summary Get post with author, category, comments and likes
+// @Description Get everything needed to show a post in one request. Only the post itself is required; any other section that can't be loaded is null and explained under errors.
+// @Tags post
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param comments_limit query int false "Number of newest comments to include, at most 100" default(10) minimum(1)
+// @Success 200 {object} models.PostFull
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetPostFull(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	commentsLimit := defaultFullPostComments
+	if c.Query("comments_limit") != "" {
+		commentsLimit, err = strconv.Atoi(c.Query("comments_limit"))
+		if err != nil {
+			writeError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if commentsLimit < 1 {
+		writeError(c, http.StatusBadRequest, errInvalidCommentsLimit)
+		return
+	}
+	if commentsLimit > maxFullPostComments {
+		commentsLimit = maxFullPostComments
+	}
+
+	ctx := c.Request.Context()
+	sections := &postSections{}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Comments and likes only need the post id, so they are fetched
+	// along with the post itself.
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+
+		result, err := h.grpcClient.CommentService().GetAll(ctx, &pbp.GetCommentQuery{
+			Page:       1,
+			Limit:      int64(commentsLimit),
+			PostId:     int64(id),
+			SortByDate: "desc",
+		})
+		if err != nil {
+			sections.fail(c, sectionComments, err)
+			return
+		}
+
+		comments := commentsResponse(h, result)
+		sections.mu.Lock()
+		sections.response.Comments = comments
+		sections.mu.Unlock()
+	}()
+	go func() {
+		defer wg.Done()
+
+		result, err := h.grpcClient.LikeService().GetLikesDislikesCount(ctx, &pbp.GetAllRequest{
+			PostId: int64(id),
+		})
+		if err != nil {
+			sections.fail(c, sectionLikes, err)
+			return
+		}
+
+		sections.mu.Lock()
+		sections.response.Likes = &models.PostLikeInfo{
+			LikesCount:    result.LikesCount,
+			DislikesCount: result.DislikesCount,
+		}
+		sections.mu.Unlock()
+	}()
+
+	post, err := h.grpcClient.PostService().Get(ctx, &pbp.GetPostRequest{Id: int64(id)})
+	if err != nil {
+		handleGrpcError(c, err)
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		user, err := h.grpcClient.UserService().Get(ctx, &pbu.IdRequest{Id: post.UserId})
+		if err != nil {
+			sections.fail(c, sectionAuthor, err)
+			return
+		}
+
+		author := parseUserModel(user)
+		sections.mu.Lock()
+		sections.response.Author = &author
+		sections.mu.Unlock()
+	}()
+
+	if post.CategoryId != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			category, err := h.grpcClient.CategoryService().Get(ctx, &pbp.IdByRequest{Id: post.CategoryId})
+			if err != nil {
+				sections.fail(c, sectionCategory, err)
+				return
+			}
+
+			sections.mu.Lock()
+			sections.response.Category = &models.Category{
+				Id:        category.Id,
+				Title:     category.Title,
+				CreatedAt: category.CreatedAt,
+			}
+			sections.mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	sections.response.Post = parsePostModel(post)
+	c.JSON(http.StatusOK, sections.response)
+}
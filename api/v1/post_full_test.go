@@ -0,0 +1,58 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+)
+
+func TestGetPostFullCommentsLimit(t *testing.T) {
+	client := newFakeClient()
+	client.users[1] = &pbu.User{Id: 1, Type: "user"}
+	client.posts[1] = &pbp.Post{Id: 1, UserId: 1}
+	for id := int64(1); id <= 150; id++ {
+		client.comments[id] = &pbp.Comment{Id: id, PostId: 1, UserId: 1}
+	}
+	router := newRouter(t, config.Config{}, client)
+
+	tests := []struct {
+		query        string
+		wantStatus   int
+		wantComments int
+	}{
+		{"", http.StatusOK, 10},
+		{"?comments_limit=1", http.StatusOK, 1},
+		{"?comments_limit=100", http.StatusOK, 100},
+		{"?comments_limit=1000000", http.StatusOK, 100},
+		{"?comments_limit=0", http.StatusBadRequest, 0},
+		{"?comments_limit=-5", http.StatusBadRequest, 0},
+		{"?comments_limit=ten", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := serve(router, http.MethodGet, "/v1/posts/1/full"+tt.query, "", nil)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%q: status = %d, want %d: %s", tt.query, w.Code, tt.wantStatus, w.Body)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var post models.PostFull
+		if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil {
+			t.Fatal(err)
+		}
+		if post.Comments == nil {
+			t.Errorf("%q: no comments: %s", tt.query, w.Body)
+			continue
+		}
+		if got := len(post.Comments.Comments); got != tt.wantComments {
+			t.Errorf("%q: got %d comments, want %d", tt.query, got, tt.wantComments)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/config"
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+	"github.com/samandar2605/medium_api_gateway/pkg/ratelimit"
+)
+
+const (
+	rateLimitPrefix = "ratelimit:"
+	// rateLimitPendingKey holds the rateLimitCheck AuthMiddleware still
+	// has to make once it knows who sent the request.
+	rateLimitPendingKey = "rate_limit_pending"
+)
+
+// rateLimitCheck is the limit of a route group.
+type rateLimitCheck struct {
+	group        string
+	limit        ratelimit.Limit
+	policyHeader string
+}
+
+// RateLimit lets every client make up to n requests per RateLimitPeriod to
+// the route group. Responses carry the RateLimit-* headers of the IETF
+// draft so clients can pace themselves.
+//
+// Requests with a token that can't be verified up front count against the
+// client IP, and once AuthMiddleware has verified the token against the
+// user as well.
+func (h *handlerV1) RateLimit(group string, n int) gin.HandlerFunc {
+	check := rateLimitCheck{
+		group:        group,
+		limit:        ratelimit.PerPeriod(n, h.cfg.RateLimitPeriod),
+		policyHeader: strconv.Itoa(n) + ";w=" + strconv.Itoa(int(h.cfg.RateLimitPeriod.Seconds())),
+	}
+
+	return func(c *gin.Context) {
+		if h.limiter == nil || !check.limit.Enabled() {
+			c.Next()
+			return
+		}
+
+		kind, client := h.rateLimitClient(c)
+		if kind == "ip" && c.GetHeader(authorizationHeaderKey) != "" {
+			c.Set(rateLimitPendingKey, check)
+		}
+		if !h.allowRequest(c, check, kind, client) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitUser makes the check RateLimit left to AuthMiddleware, now that
+// payload tells who sent the request. On failure the response has already
+// been written.
+func (h *handlerV1) limitUser(c *gin.Context, payload Payload) bool {
+	check, ok := c.Value(rateLimitPendingKey).(rateLimitCheck)
+	if !ok {
+		return true
+	}
+	c.Set(rateLimitPendingKey, nil)
+
+	return h.allowRequest(c, check, "user", strconv.FormatInt(payload.UserID, 10))
+}
+
+// allowRequest counts the request against client's bucket and rejects it
+// with 429 once the bucket is empty.
+func (h *handlerV1) allowRequest(c *gin.Context, check rateLimitCheck, kind, client string) bool {
+	key := rateLimitPrefix + check.group + ":" + kind + ":" + client
+	res, err := h.limiter.Allow(c.Request.Context(), key, check.limit)
+	if err != nil {
+		// A broken limiter must not take the whole API down.
+		log.Printf("failed to check rate limit: %v", err)
+		return true
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(check.limit.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", ceilSeconds(res.Reset))
+	c.Header("RateLimit-Policy", check.policyHeader)
+
+	if !res.Allowed {
+		metrics.RateLimited.WithLabelValues(check.group, kind).Inc()
+		c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+		writeError(c, http.StatusTooManyRequests, ErrRateLimited)
+		return false
+	}
+	return true
+}
+
+// rateLimitClient tells who the request is from: the user, if their token
+// can be verified without asking user_service or user_service already
+// verified it recently, or else the client IP. An unverified token says
+// nothing, anyone could make one up to get a fresh bucket.
+func (h *handlerV1) rateLimitClient(c *gin.Context) (string, string) {
+	if payload, ok := c.Value(authorizationPayloadKey).(Payload); ok {
+		return "user", strconv.FormatInt(payload.UserID, 10)
+	}
+
+	accessToken := c.GetHeader(authorizationHeaderKey)
+	if accessToken == "" {
+		return "ip", c.ClientIP()
+	}
+
+	if h.tokens != nil && h.cfg.AuthVerifyMode == config.AuthVerifyLocal {
+		claims, err := h.tokens.Verify(strings.TrimPrefix(accessToken, "Bearer "))
+		if err == nil {
+			return "user", strconv.FormatInt(claims.UserID, 10)
+		}
+	}
+	if payload, ok := h.verified.owner(accessToken); ok {
+		return "user", strconv.FormatInt(payload.UserID, 10)
+	}
+	return "ip", c.ClientIP()
+}
@@ -0,0 +1,105 @@
+package v1_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+)
+
+func TestRateLimitPerUserWithRemoteVerification(t *testing.T) {
+	client := newFakeClient()
+	expiredAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	client.sessions["token-1"] = &pbu.AuthPayload{Id: "1", UserId: 1, UserType: "user", ExpiredAt: expiredAt}
+	client.sessions["token-2"] = &pbu.AuthPayload{Id: "2", UserId: 2, UserType: "user", ExpiredAt: expiredAt}
+
+	router := newRouter(t, config.Config{
+		AuthVerifyMode:  config.AuthVerifyRemote,
+		AuthCacheSize:   100,
+		AuthCacheTTL:    time.Minute,
+		RateLimitPeriod: time.Minute,
+		LikeRateLimit:   3,
+	}, client)
+
+	like := func(accessToken string) int {
+		header := http.Header{"Authorization": {"Bearer " + accessToken}}
+		return serve(router, http.MethodPost, "/v1/likes", `{"post_id":1,"status":true}`, header).Code
+	}
+
+	// Both users send from the same address; each of them has their own
+	// bucket nonetheless.
+	for i := 0; i < 3; i++ {
+		if code := like("token-1"); code != http.StatusOK {
+			t.Fatalf("request %d of user 1: status = %d, want %d", i+1, code, http.StatusOK)
+		}
+	}
+	if code := like("token-1"); code != http.StatusTooManyRequests {
+		t.Fatalf("request 4 of user 1: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := like("token-2"); code != http.StatusOK {
+			t.Fatalf("request %d of user 2: status = %d, want %d", i+1, code, http.StatusOK)
+		}
+	}
+	if code := like("token-2"); code != http.StatusTooManyRequests {
+		t.Fatalf("request 4 of user 2: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+// TestRateLimitPerUserWithoutVerifyCache checks that users are limited
+// even when nothing tells who they are before AuthMiddleware verified
+// their token, however many addresses they send from.
+func TestRateLimitPerUserWithoutVerifyCache(t *testing.T) {
+	client := newFakeClient()
+	client.sessions["token-1"] = &pbu.AuthPayload{Id: "1", UserId: 1, UserType: "user"}
+
+	router := newRouter(t, config.Config{
+		AuthVerifyMode:  config.AuthVerifyRemote,
+		RateLimitPeriod: time.Minute,
+		LikeRateLimit:   2,
+	}, client)
+
+	var codes []int
+	for _, addr := range []string{"192.0.2.10:1234", "192.0.2.11:1234", "192.0.2.12:1234"} {
+		header := http.Header{"Authorization": {"Bearer token-1"}}
+		codes = append(codes, serveFrom(router, addr, http.MethodPost, "/v1/likes", `{"post_id":1,"status":true}`, header).Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("statuses = %v, want [200 200 429]", codes)
+	}
+}
+
+// TestRateLimitIgnoresUntrustedForwardedFor checks that clients can't get a
+// fresh bucket by naming another address in X-Forwarded-For, unless they
+// are a trusted proxy.
+func TestRateLimitIgnoresUntrustedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		wantLimited    []bool
+	}{
+		{"no trusted proxies", nil, []bool{false, false, true}},
+		{"trusted proxy", []string{"192.0.2.0/24"}, []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		router := newRouter(t, config.Config{
+			TrustedProxies:    tt.trustedProxies,
+			RateLimitPeriod:   time.Minute,
+			CategoryRateLimit: 2,
+		}, newFakeClient())
+
+		var limited []bool
+		for _, ip := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+			header := http.Header{"X-Forwarded-For": {ip}}
+			w := serveFrom(router, "192.0.2.10:1234", http.MethodPost, "/v1/categories", `{}`, header)
+			limited = append(limited, w.Code == http.StatusTooManyRequests)
+		}
+		if !reflect.DeepEqual(limited, tt.wantLimited) {
+			t.Errorf("%s: limited = %v, want %v", tt.name, limited, tt.wantLimited)
+		}
+	}
+}
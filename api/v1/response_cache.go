@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+)
+
+// Namespaces of cached responses. A change to anything in a namespace
+// invalidates every response cached in it.
+const (
+	CacheNamespacePosts      = "posts"
+	CacheNamespaceCategories = "categories"
+)
+
+type cachedResponse struct {
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	StoredAt    time.Time `json:"stored_at"`
+}
+
+// responseRecorder keeps a copy of the body written by the handler and
+// marks successful responses as cacheable by clients too.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body         bytes.Buffer
+	cacheControl string
+}
+
+func (w *responseRecorder) WriteHeader(code int) {
+	if code == http.StatusOK {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// CacheResponse serves the route's successful responses from the response
+// cache for up to ttl. Requests with Cache-Control: no-cache skip the
+// lookup but still refresh the cache.
+func (h *handlerV1) CacheResponse(namespace string, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.responses == nil || ttl <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		generation, err := h.responses.Generation(ctx, namespace)
+		if err != nil {
+			log.Printf("failed to read response cache generation: %v", err)
+			c.Next()
+			return
+		}
+		key := namespace + ":" + strconv.FormatInt(generation, 10) + ":" + responseCacheKey(c.Request)
+
+		if !strings.Contains(c.GetHeader("Cache-Control"), "no-cache") {
+			if cached, ok := h.cachedResponse(c, key); ok {
+				metrics.ResponseCacheRequests.WithLabelValues(namespace, "hit").Inc()
+
+				age := time.Since(cached.StoredAt)
+				c.Header("Age", strconv.Itoa(int(age.Seconds())))
+				c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int((ttl-age).Seconds())))
+				c.Data(http.StatusOK, cached.ContentType, cached.Body)
+				c.Abort()
+				return
+			}
+			metrics.ResponseCacheRequests.WithLabelValues(namespace, "miss").Inc()
+		} else {
+			metrics.ResponseCacheRequests.WithLabelValues(namespace, "bypass").Inc()
+		}
+
+		recorder := &responseRecorder{
+			ResponseWriter: c.Writer,
+			cacheControl:   "public, max-age=" + strconv.Itoa(int(ttl.Seconds())),
+		}
+		c.Writer = recorder
+		c.Next()
+
+		if recorder.Status() != http.StatusOK {
+			return
+		}
+
+		data, err := json.Marshal(cachedResponse{
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+			StoredAt:    time.Now(),
+		})
+		if err == nil {
+			err = h.responses.Set(ctx, key, data, ttl)
+		}
+		if err != nil {
+			log.Printf("failed to cache response: %v", err)
+		}
+	}
+}
+
+func (h *handlerV1) cachedResponse(c *gin.Context, key string) (cachedResponse, bool) {
+	data, ok, err := h.responses.Get(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("failed to read response cache: %v", err)
+		return cachedResponse{}, false
+	}
+	if !ok {
+		return cachedResponse{}, false
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return cachedResponse{}, false
+	}
+	return cached, true
+}
+
+// responseCacheKey is the request's path and query, with the parameters
+// sorted and empty ones dropped, so equivalent requests share an entry.
+func responseCacheKey(r *http.Request) string {
+	query := r.URL.Query()
+	for name, values := range query {
+		if len(values) == 0 || values[0] == "" {
+			delete(query, name)
+		}
+	}
+
+	normalized := query.Encode()
+	if normalized == "" {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + normalized
+}
+
+// invalidateResponses drops every cached response of namespace after a
+// change made through the gateway.
+func (h *handlerV1) invalidateResponses(c *gin.Context, namespace string) {
+	if h.responses == nil {
+		return
+	}
+
+	if err := h.responses.Invalidate(c.Request.Context(), namespace); err != nil {
+		log.Printf("failed to invalidate %s response cache: %v", namespace, err)
+	}
+}
@@ -0,0 +1,157 @@
+package v1_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/api"
+	"github.com/samandar2605/medium_api_gateway/config"
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	"github.com/samandar2605/medium_api_gateway/pkg/cache"
+)
+
+func newCacheRouter(t *testing.T) (http.Handler, *fakeClient) {
+	t.Helper()
+
+	client := newFakeClient()
+	client.posts[1] = &pbp.Post{Id: 1, UserId: 1, Title: "title"}
+	client.categories[1] = &pbp.Category{Id: 1, Title: "category"}
+
+	router := newRouterWith(t, api.RouterOptions{
+		Cfg: &config.Config{
+			CategoryCacheTTL: time.Minute,
+			PostListCacheTTL: time.Minute,
+			PostCacheTTL:     time.Minute,
+		},
+		GrpcClient:    client,
+		ResponseCache: cache.NewLocal(100),
+	})
+	return router, client
+}
+
+// countRPCs serves the request and tells how many rpcs answering it took.
+func countRPCs(router http.Handler, client *fakeClient, method, path, body string, header http.Header) (*httptest.ResponseRecorder, int64) {
+	before := client.rpcs.Load()
+	w := serve(router, method, path, body, header)
+	return w, client.rpcs.Load() - before
+}
+
+func TestCacheResponseServesCachedReads(t *testing.T) {
+	router, client := newCacheRouter(t)
+
+	first, rpcs := countRPCs(router, client, http.MethodGet, "/v1/posts/1", "", nil)
+	if first.Code != http.StatusOK || rpcs != 1 {
+		t.Fatalf("first read: status %d after %d rpcs, want 200 after 1", first.Code, rpcs)
+	}
+	if age := first.Header().Get("Age"); age != "" {
+		t.Errorf("first read: Age = %q, want none", age)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("first read: Cache-Control = %q, want %q", got, "public, max-age=60")
+	}
+
+	second, rpcs := countRPCs(router, client, http.MethodGet, "/v1/posts/1", "", nil)
+	if second.Code != http.StatusOK || rpcs != 0 {
+		t.Fatalf("second read: status %d after %d rpcs, want 200 from the cache", second.Code, rpcs)
+	}
+	if age := second.Header().Get("Age"); age != "0" {
+		t.Errorf("second read: Age = %q, want %q", age, "0")
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("second read: body %s, want %s", second.Body, first.Body)
+	}
+
+	header := http.Header{"Cache-Control": {"no-cache"}}
+	if _, rpcs := countRPCs(router, client, http.MethodGet, "/v1/posts/1", "", header); rpcs != 1 {
+		t.Errorf("read with no-cache: %d rpcs, want 1", rpcs)
+	}
+}
+
+func TestCacheResponseNormalizesQueries(t *testing.T) {
+	router, client := newCacheRouter(t)
+
+	tests := []struct {
+		path   string
+		cached bool
+	}{
+		{"/v1/posts?page=1&limit=10", false},
+		{"/v1/posts?limit=10&page=1", true},
+		{"/v1/posts?limit=10&user_id=&page=1", true},
+		{"/v1/posts?limit=5&page=1", false},
+	}
+	for _, tt := range tests {
+		w, rpcs := countRPCs(router, client, http.MethodGet, tt.path, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.path, w.Code, http.StatusOK)
+		}
+		if cached := rpcs == 0; cached != tt.cached {
+			t.Errorf("%s: served from the cache = %t, want %t", tt.path, cached, tt.cached)
+		}
+	}
+}
+
+func TestCacheResponseInvalidatedByChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		// reads are cached before the change and must miss after it.
+		reads  []string
+		method string
+		path   string
+		body   string
+		header http.Header
+	}{
+		{
+			name:   "post update",
+			reads:  []string{"/v1/posts/1", "/v1/posts"},
+			method: http.MethodPut,
+			path:   "/v1/posts/1",
+			body:   `{"title":"edited"}`,
+			header: authHeader(t, 1, "user"),
+		},
+		{
+			name:   "post create",
+			reads:  []string{"/v1/posts/1", "/v1/posts"},
+			method: http.MethodPost,
+			path:   "/v1/posts",
+			body:   `{"title":"new"}`,
+			header: authHeader(t, 1, "user"),
+		},
+		{
+			name:   "post delete",
+			reads:  []string{"/v1/posts"},
+			method: http.MethodDelete,
+			path:   "/v1/posts/1",
+			header: authHeader(t, 1, "user"),
+		},
+		{
+			name:   "category update",
+			reads:  []string{"/v1/categories/1"},
+			method: http.MethodPut,
+			path:   "/v1/categories/1",
+			body:   `{"title":"edited"}`,
+			header: authHeader(t, 2, "admin"),
+		},
+	}
+	for _, tt := range tests {
+		router, client := newCacheRouter(t)
+
+		for _, path := range tt.reads {
+			countRPCs(router, client, http.MethodGet, path, "", nil)
+			if _, rpcs := countRPCs(router, client, http.MethodGet, path, "", nil); rpcs != 0 {
+				t.Fatalf("%s: %s isn't cached", tt.name, path)
+			}
+		}
+
+		if w := serve(router, tt.method, tt.path, tt.body, tt.header); w.Code >= 300 {
+			t.Fatalf("%s: status = %d: %s", tt.name, w.Code, w.Body)
+		}
+
+		for _, path := range tt.reads {
+			if _, rpcs := countRPCs(router, client, http.MethodGet, path, "", nil); rpcs == 0 {
+				t.Errorf("%s: %s still served from the cache", tt.name, path)
+			}
+		}
+	}
+}
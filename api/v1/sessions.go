@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
+)
+
+// Keys of the state kept in the store.
+const (
+	revokedTokenPrefix  = "revoked:token:"
+	revokedUserPrefix   = "revoked:user:"
+	refreshTokenPrefix  = "refresh:token:"
+	refreshUsedPrefix   = "refresh:used:"
+	refreshFamilyPrefix = "refresh:family-revoked:"
+)
+
+// refreshSession is what is stored for every refresh token. All tokens
+// handed out by rotating one another form a family; reusing any of them
+// revokes the whole family, since it means one of them was stolen.
+type refreshSession struct {
+	Family          string    `json:"family"`
+	FamilyCreatedAt time.Time `json:"family_created_at"`
+	UserID          int64     `json:"user_id"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+func (h *handlerV1) refreshEnabled() bool {
+	return h.tokens != nil && h.store != nil
+}
+
+func hashToken(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+// startSession begins a new refresh token family for a user who just
+// logged in and returns its first refresh token.
+func (h *handlerV1) startSession(ctx context.Context, userID int64) (string, error) {
+	family, err := token.NewID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return h.issueRefreshToken(ctx, refreshSession{
+		Family:          family,
+		FamilyCreatedAt: now,
+		UserID:          userID,
+		ExpiresAt:       now.Add(h.cfg.RefreshTokenTTL),
+	})
+}
+
+func (h *handlerV1) issueRefreshToken(ctx context.Context, s refreshSession) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(b)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	err = h.store.Set(ctx, refreshTokenPrefix+hashToken(refreshToken), string(data), time.Until(s.ExpiresAt))
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// lookupRefreshToken returns the session of a refresh token that is still
+// valid: not expired and not revoked, on its own or with all of the user's
+// sessions.
+func (h *handlerV1) lookupRefreshToken(ctx context.Context, refreshToken string) (*refreshSession, error) {
+	data, err := h.store.Get(ctx, refreshTokenPrefix+hashToken(refreshToken))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	var s refreshSession
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	_, err = h.store.Get(ctx, refreshFamilyPrefix+s.Family)
+	switch {
+	case err == nil:
+		return nil, ErrInvalidRefreshToken
+	case !errors.Is(err, store.ErrNotFound):
+		return nil, err
+	}
+
+	revokedBefore, err := h.userRevokedBefore(ctx, s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if s.FamilyCreatedAt.Before(revokedBefore) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return &s, nil
+}
+
+// useRefreshToken marks the refresh token of session s as used. Every
+// refresh token can be used once; presenting it again revokes its family.
+// It must only be called once the replacement tokens are ready, so that a
+// failure on the way doesn't use the token up and make the client's retry
+// look like reuse.
+func (h *handlerV1) useRefreshToken(ctx context.Context, refreshToken string, s *refreshSession) error {
+	first, err := h.store.SetNX(ctx, refreshUsedPrefix+hashToken(refreshToken), "1", time.Until(s.ExpiresAt))
+	if err != nil {
+		return err
+	}
+	if !first {
+		if err := h.revokeFamily(ctx, s.Family); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (h *handlerV1) revokeFamily(ctx context.Context, family string) error {
+	return h.store.Set(ctx, refreshFamilyPrefix+family, "1", h.cfg.RefreshTokenTTL)
+}
+
+// revokeToken rejects the access token with the given id from now on. It
+// only has to be remembered until the token expires anyway.
+func (h *handlerV1) revokeToken(ctx context.Context, payload *Payload) error {
+	ttl := h.cfg.RefreshTokenTTL
+	if expiresAt, err := time.Parse(time.RFC3339, payload.ExpiredAt); err == nil {
+		ttl = time.Until(expiresAt)
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return h.store.Set(ctx, revokedTokenPrefix+payload.ID, "1", ttl)
+}
+
+// revokeUser rejects every access and refresh token of the user issued
+// until now.
+func (h *handlerV1) revokeUser(ctx context.Context, userID int64) error {
+	return h.store.Set(ctx, revokedUserPrefix+strconv.FormatInt(userID, 10),
+		time.Now().Format(time.RFC3339Nano), h.cfg.RefreshTokenTTL)
+}
+
+func (h *handlerV1) userRevokedBefore(ctx context.Context, userID int64) (time.Time, error) {
+	value, err := h.store.Get(ctx, revokedUserPrefix+strconv.FormatInt(userID, 10))
+	if errors.Is(err, store.ErrNotFound) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	revokedBefore, _ := time.Parse(time.RFC3339Nano, value)
+	return revokedBefore, nil
+}
+
+// isRevoked reports whether the access token payload was taken from has
+// been revoked by a logout.
+func (h *handlerV1) isRevoked(ctx context.Context, payload Payload) (bool, error) {
+	if payload.ID != "" {
+		_, err := h.store.Get(ctx, revokedTokenPrefix+payload.ID)
+		switch {
+		case err == nil:
+			return true, nil
+		case !errors.Is(err, store.ErrNotFound):
+			return false, err
+		}
+	}
+
+	revokedBefore, err := h.userRevokedBefore(ctx, payload.UserID)
+	if err != nil || revokedBefore.IsZero() {
+		return false, err
+	}
+
+	issuedAt, err := time.Parse(time.RFC3339Nano, payload.IssuedAt)
+	if err != nil {
+		// A token that can't tell when it was issued may well predate
+		// the revocation.
+		log.Printf("rejecting token of revoked user %d: issued_at %q is not RFC 3339", payload.UserID, payload.IssuedAt)
+		return true, nil
+	}
+	// Tokens issued by user_service only carry whole seconds, so one
+	// issued in the same second as the revocation is rejected as well.
+	return !issuedAt.After(revokedBefore), nil
+}
@@ -1,8 +1,6 @@
 package v1
 
 import (
-	"context"
-	"errors"
 	"net/http"
 	"strconv"
 
@@ -21,6 +19,7 @@ import (
 // @Produce json
 // @Param user body models.CreateUserRequest true "User"
 // @Success 201 {object} models.User
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) CreateUser(c *gin.Context) {
 	var (
@@ -29,26 +28,26 @@ func (h *handlerV1) CreateUser(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	payload, err := h.GetAuthPayload(c)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
-		return
-	}
-
-	if payload.UserType != "superadmin" {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
-		return
+	// Creating anyone but a plain user grants a role, which must stay
+	// with superadmins whatever the policy file says.
+	if req.Type != userTypeUser {
+		payload, err := h.GetAuthPayload(c)
+		if err != nil {
+			writeError(c, http.StatusUnauthorized, err)
+			return
+		}
+		if payload.UserType != userTypeSuperadmin {
+			h.audit(c, auditEntry{Action: "user.create", To: req.Type, Outcome: "denied"})
+			writeError(c, http.StatusForbidden, ErrForbidden)
+			return
+		}
 	}
 
-	user, err := h.grpcClient.UserService().Create(context.Background(), &pbu.User{
+	user, err := h.grpcClient.UserService().Create(c.Request.Context(), &pbu.User{
 		FirstName:       req.FirstName,
 		LastName:        req.LastName,
 		PhoneNumber:     req.PhoneNumber,
@@ -60,23 +59,14 @@ func (h *handlerV1) CreateUser(c *gin.Context) {
 		Type:            req.Type,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
+	if req.Type != userTypeUser {
+		h.audit(c, auditEntry{Action: "user.create", TargetID: user.Id, To: req.Type, Outcome: "granted"})
+	}
 
-	c.JSON(http.StatusCreated, models.User{
-		ID:              user.Id,
-		FirstName:       user.FirstName,
-		LastName:        user.LastName,
-		PhoneNumber:     user.PhoneNumber,
-		Email:           user.Email,
-		Gender:          user.Gender,
-		Username:        user.Username,
-		Password:        user.Password,
-		ProfileImageUrl: user.ProfileImageUrl,
-		Type:            user.Type,
-		CreatedAt:       user.CreatedAt,
-	})
+	c.JSON(http.StatusCreated, parseUserModel(user))
 }
 
 // @Router /users/{id} [get]
@@ -86,38 +76,24 @@ func (h *handlerV1) CreateUser(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-None-Match header string false "ETag of the copy the client has"
 // @Success 200 {object} models.User
+// @Success 304 "Not Modified"
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) GetUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	user, err := h.grpcClient.UserService().Get(context.Background(), &pbu.IdRequest{Id: int64(id)})
+	user, err := h.grpcClient.UserService().Get(c.Request.Context(), &pbu.IdRequest{Id: int64(id)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, models.User{
-		ID:              user.Id,
-		FirstName:       user.FirstName,
-		LastName:        user.LastName,
-		PhoneNumber:     user.PhoneNumber,
-		Password:        user.Password,
-		Email:           user.Email,
-		Gender:          user.Gender,
-		Username:        user.Username,
-		ProfileImageUrl: user.ProfileImageUrl,
-		Type:            user.Type,
-		CreatedAt:       user.CreatedAt,
-	})
+	c.JSON(http.StatusOK, parseUserModel(user))
 }
 
 // @Router /users [get]
@@ -132,17 +108,17 @@ func (h *handlerV1) GetUser(c *gin.Context) {
 func (h *handlerV1) GetAllUsers(c *gin.Context) {
 	req, err := validateGetAllParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.grpcClient.UserService().GetAll(context.Background(), &pbu.GetAllUsersRequest{
+	result, err := h.grpcClient.UserService().GetAll(c.Request.Context(), &pbu.GetAllUsersRequest{
 		Page:   req.Page,
 		Limit:  req.Limit,
 		Search: req.Search,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		handleGrpcError(c, err)
 		return
 	}
 
@@ -163,13 +139,14 @@ func getUsersResponse(data *pbu.GetAllUsersResponse) *models.GetAllUsersResponse
 	return &response
 }
 
+// parseUserModel is how every user ends up in a response. The password hash
+// the backend sends along is deliberately left behind.
 func parseUserModel(user *pbu.User) models.User {
 	return models.User{
 		ID:              user.Id,
 		FirstName:       user.FirstName,
 		LastName:        user.LastName,
 		PhoneNumber:     user.PhoneNumber,
-		Password:        user.Password,
 		Email:           user.Email,
 		Gender:          user.Gender,
 		Username:        user.Username,
@@ -181,50 +158,53 @@ func parseUserModel(user *pbu.User) models.User {
 
 // @Security ApiKeyAuth
 // @Summary Update a user
-// @Description Update a users
+// @Description Update a user. The user's type can't be changed yet, user_service doesn't support it.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the version the update is based on"
 // @Param user body models.UpdateUserRequest true "user"
 // @Success 200 {object} models.User
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /users/{id} [put]
 func (h *handlerV1) UpdateUser(ctx *gin.Context) {
 	var (
-		req models.User
+		req models.UpdateUserRequest
 	)
 
 	err := ctx.ShouldBindJSON(&req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
-		return
-	}
-
-	payload, err := h.GetAuthPayload(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	if payload.UserID != int64(id) && payload.UserType != "superadmin" {
-		err := errors.New("authorization header is not provided")
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+	if !h.authorizeOwner(ctx, int64(id)) {
 		return
 	}
 
-	user, err := h.grpcClient.UserService().Update(context.Background(), &pbu.UpdateUser{
+	if ctx.GetHeader("If-Match") != "" {
+		current, err := h.grpcClient.UserService().Get(ctx.Request.Context(), &pbu.IdRequest{Id: int64(id)})
+		if err != nil {
+			handleGrpcError(ctx, err)
+			return
+		}
+		if !checkIfMatch(ctx, parseUserModel(current)) {
+			return
+		}
+	}
+
+	// pbu.UpdateUser has no type field, so there is no way to promote or
+	// demote an existing user yet. Once medium_protos adds one, changing
+	// it gets its own superadmin-only, audited endpoint,
+	// PUT /users/:id/role, rather than going through here.
+	user, err := h.grpcClient.UserService().Update(ctx.Request.Context(), &pbu.UpdateUser{
 		Id:              int64(id),
 		FirstName:       req.FirstName,
 		LastName:        req.LastName,
@@ -234,25 +214,13 @@ func (h *handlerV1) UpdateUser(ctx *gin.Context) {
 		ProfileImageUrl: req.ProfileImageUrl,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, models.User{
-		ID:              user.Id,
-		FirstName:       user.FirstName,
-		LastName:        user.LastName,
-		PhoneNumber:     user.PhoneNumber,
-		Password:        user.Password,
-		Email:           user.Email,
-		Gender:          user.Gender,
-		Username:        user.Username,
-		ProfileImageUrl: user.ProfileImageUrl,
-		Type:            user.Type,
-		CreatedAt:       user.CreatedAt,
-	})
+	updated := parseUserModel(user)
+	setETag(ctx, updated)
+	ctx.JSON(http.StatusOK, updated)
 }
 
 // @Security ApiKeyAuth
@@ -262,41 +230,40 @@ func (h *handlerV1) UpdateUser(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag of the version to delete"
+// @Success 200 {object} models.ResponseOK
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /users/{id} [delete]
 func (h *handlerV1) DeleteUser(ctx *gin.Context) {
 	id, err := strconv.ParseInt(ctx.Param("id"), 10, 0)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": "failed to convert",
-		})
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	payload, err := h.GetAuthPayload(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: err.Error(),
-		})
+	if !h.authorizeOwner(ctx, id) {
 		return
 	}
 
-	if int(id) != int(payload.UserID) && payload.UserType != "superadmin" {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
-		return
+	if ctx.GetHeader("If-Match") != "" {
+		current, err := h.grpcClient.UserService().Get(ctx.Request.Context(), &pbu.IdRequest{Id: id})
+		if err != nil {
+			handleGrpcError(ctx, err)
+			return
+		}
+		if !checkIfMatch(ctx, parseUserModel(current)) {
+			return
+		}
 	}
 
-	_, err = h.grpcClient.UserService().Delete(context.Background(), &pbu.DeleteUserRequest{
+	_, err = h.grpcClient.UserService().Delete(ctx.Request.Context(), &pbu.DeleteUserRequest{
 		Id: id,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: err.Error(),
-		})
+		handleGrpcError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "successful delete method",
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "successful delete method",
 	})
 }
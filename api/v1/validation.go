@@ -0,0 +1,169 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/samandar2605/medium_api_gateway/api/models"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+var ErrValidation = errors.New("request validation failed")
+
+// Catalog keys of the messages explaining why a field was rejected.
+const (
+	msgValidationRequired  = "validation.required"
+	msgValidationEmail     = "validation.email"
+	msgValidationOneOf     = "validation.oneof"
+	msgValidationMin       = "validation.min"
+	msgValidationMinLength = "validation.min_length"
+	msgValidationMax       = "validation.max"
+	msgValidationMaxLength = "validation.max_length"
+	msgValidationType      = "validation.type"
+	msgValidationInvalid   = "validation.invalid"
+)
+
+var validationMessageKeys = []string{
+	msgValidationRequired,
+	msgValidationEmail,
+	msgValidationOneOf,
+	msgValidationMin,
+	msgValidationMinLength,
+	msgValidationMax,
+	msgValidationMaxLength,
+	msgValidationType,
+	msgValidationInvalid,
+}
+
+var registerTagNameOnce sync.Once
+
+// registerJSONFieldNames makes the validator report fields by their JSON
+// names, which are the names clients know them by.
+func registerJSONFieldNames() {
+	registerTagNameOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	})
+}
+
+// validationError carries the per-field reasons a request was rejected for.
+type validationError struct {
+	fields []models.FieldError
+}
+
+func (e *validationError) Error() string {
+	return ErrValidation.Error()
+}
+
+func (e *validationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// fieldErrors returns the field level details of err, if it has any. It
+// understands validator errors, JSON type mismatches and errors already
+// converted by grpcError.
+func fieldErrors(l *i18n.Localizer, err error) []models.FieldError {
+	var (
+		verr    *validationError
+		vErrs   validator.ValidationErrors
+		typeErr *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &verr):
+		return verr.fields
+	case errors.As(err, &vErrs):
+		fields := make([]models.FieldError, 0, len(vErrs))
+		for _, fe := range vErrs {
+			fields = append(fields, models.FieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: validationMessage(l, fe),
+			})
+		}
+		return fields
+	case errors.As(err, &typeErr):
+		params := map[string]string{"param": typeErr.Type.String()}
+		return []models.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: l.Translate(msgValidationType, "must be of type "+typeErr.Type.String(), params),
+		}}
+	}
+
+	return nil
+}
+
+func validationMessage(l *i18n.Localizer, fe validator.FieldError) string {
+	params := map[string]string{
+		"param": fe.Param(),
+		"rule":  fe.Tag(),
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return l.Translate(msgValidationRequired, "is required", params)
+	case "email":
+		return l.Translate(msgValidationEmail, "must be a valid email address", params)
+	case "oneof":
+		params["param"] = strings.ReplaceAll(fe.Param(), " ", ", ")
+		return l.Translate(msgValidationOneOf, "must be one of: "+params["param"], params)
+	case "min":
+		if fe.Kind() == reflect.String {
+			return l.Translate(msgValidationMinLength,
+				fmt.Sprintf("must be at least %s characters long", fe.Param()), params)
+		}
+		return l.Translate(msgValidationMin, "must be at least "+fe.Param(), params)
+	case "max":
+		if fe.Kind() == reflect.String {
+			return l.Translate(msgValidationMaxLength,
+				fmt.Sprintf("must be at most %s characters long", fe.Param()), params)
+		}
+		return l.Translate(msgValidationMax, "must be at most "+fe.Param(), params)
+	}
+
+	return l.Translate(msgValidationInvalid, fmt.Sprintf("failed on the '%s' rule", fe.Tag()), params)
+}
+
+// badRequestDetails decodes the google.rpc.BadRequest details a backend
+// attached to its status, if any.
+func badRequestDetails(s *status.Status) []models.FieldError {
+	var fields []models.FieldError
+	for _, detail := range s.Details() {
+		br, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+
+		for _, v := range br.GetFieldViolations() {
+			fields = append(fields, models.FieldError{
+				Field:   v.GetField(),
+				Message: v.GetDescription(),
+			})
+		}
+	}
+	return fields
+}
@@ -1,27 +1,143 @@
 package main
 
 import (
+	"context"
+	"io"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/lib/pq"
 	"github.com/samandar2605/medium_api_gateway/api"
 	"github.com/samandar2605/medium_api_gateway/config"
+	"github.com/samandar2605/medium_api_gateway/pkg/cache"
 	grpcPkg "github.com/samandar2605/medium_api_gateway/pkg/grpc_client"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+	"github.com/samandar2605/medium_api_gateway/pkg/policy"
+	"github.com/samandar2605/medium_api_gateway/pkg/ratelimit"
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
+	"github.com/samandar2605/medium_api_gateway/pkg/tracing"
 )
 
 func main() {
 	cfg := config.Load(".")
 
+	shutdownTracing, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("failed to init tracing: %v", err)
+	}
+
+	catalog, err := i18n.Load()
+	if err != nil {
+		log.Fatalf("failed to load message catalogs: %v", err)
+	}
+
+	pol, err := policy.Load(cfg.AuthPolicyPath)
+	if err != nil {
+		log.Fatalf("failed to load auth policy: %v", err)
+	}
+
+	// The key is needed to verify tokens locally, and otherwise only to
+	// issue tokens on refresh, which is disabled without it.
+	var tokens *token.Maker
+	if cfg.AuthSecretKey != "" || cfg.AuthVerifyMode == config.AuthVerifyLocal {
+		tokens, err = token.NewMaker(cfg.AuthSecretKey, token.ClaimLayout{
+			ID:        cfg.AuthClaimID,
+			UserID:    cfg.AuthClaimUserID,
+			Email:     cfg.AuthClaimEmail,
+			UserType:  cfg.AuthClaimUserType,
+			IssuedAt:  cfg.AuthClaimIssuedAt,
+			ExpiredAt: cfg.AuthClaimExpiredAt,
+			UnixTimes: cfg.AuthClaimUnixTimes,
+		})
+		if err != nil && cfg.AuthVerifyMode == config.AuthVerifyLocal {
+			log.Fatalf("failed to set up token verification: %v", err)
+		} else if err != nil {
+			log.Printf("refresh tokens are disabled: %v", err)
+		}
+	}
+
+	kv, err := store.New(cfg)
+	if err != nil {
+		log.Fatalf("failed to set up store: %v", err)
+	}
+
 	grpcConn, err := grpcPkg.New(cfg)
 	if err != nil {
 		log.Fatalf("failed to get grpc connections: %v", err)
 	}
-	apiServer := api.New(&api.RouterOptions{
-		Cfg:         &cfg,
-		GrpcClient: grpcConn,
+	apiServer, err := api.New(&api.RouterOptions{
+		Cfg:           &cfg,
+		GrpcClient:    grpcConn,
+		Catalog:       catalog,
+		Tokens:        tokens,
+		Policy:        pol,
+		Store:         kv,
+		RateLimiter:   ratelimit.New(kv),
+		ResponseCache: cache.New(cfg.ResponseCacheSize, kv),
 	})
-	err = apiServer.Run(cfg.HttpPort)
 	if err != nil {
+		log.Fatalf("failed to set up router: %v", err)
+	}
+
+	servers := []*http.Server{
+		{Addr: cfg.HttpPort, Handler: apiServer},
+		{Addr: cfg.MetricsPort, Handler: api.NewInternal(&api.RouterOptions{
+			Cfg:        &cfg,
+			GrpcClient: grpcConn,
+			Catalog:    catalog,
+			Tokens:     tokens,
+			Policy:     pol,
+			Store:      kv,
+		})},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := pol.Watch(ctx); err != nil {
+		log.Fatalf("failed to watch auth policy: %v", err)
+	}
+
+	serverErr := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			log.Printf("http server listening on %s", server.Addr)
+			serverErr <- server.ListenAndServe()
+		}(server)
+	}
+
+	select {
+	case err = <-serverErr:
+		grpcConn.Close()
 		log.Fatalf("failed to run server: %v", err)
+	case <-ctx.Done():
+		log.Printf("shutting down, draining in-flight requests for up to %s", cfg.ShutdownTimeout)
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("failed to shutdown server on %s gracefully: %v", server.Addr, err)
+		}
+	}
+
+	if err := grpcConn.Close(); err != nil {
+		log.Printf("failed to close grpc connections: %v", err)
+	}
+
+	if closer, ok := kv.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("failed to close store: %v", err)
+		}
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("failed to flush traces: %v", err)
 	}
 }
@@ -1,17 +1,153 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
+// Values of AuthVerifyMode.
+const (
+	AuthVerifyLocal  = "local"
+	AuthVerifyRemote = "remote"
+)
+
 type Config struct {
 	HttpPort            string
+	MetricsPort         string
 	UserServiceGrpcPort string
 	UserServiceHost     string
 	PostServiceGrpcPort string
 	PostServiceHost     string
 	AuthSecretKey       string
+
+	// AuthVerifyMode is "remote", the default, to ask user_service about
+	// every token, or "local" to verify tokens in the gateway using
+	// AuthSecretKey, which then has to be set. With AuthRemoteFallback
+	// set, tokens the gateway can't decide on locally are still passed to
+	// user_service.
+	//
+	// Permissions are always evaluated against the policy at
+	// AuthPolicyPath, or the built-in one when empty. The file is reloaded
+	// when it changes.
+	AuthVerifyMode     string
+	AuthPolicyPath     string
+	AuthRemoteFallback bool
+
+	// AuthClaim* name the claims of the access tokens user_service issues,
+	// which are read when verifying locally and written into the tokens
+	// issued on refresh. With AuthClaimUnixTimes set, times are written as
+	// seconds since the epoch instead of RFC 3339 strings.
+	AuthClaimID        string
+	AuthClaimUserID    string
+	AuthClaimEmail     string
+	AuthClaimUserType  string
+	AuthClaimIssuedAt  string
+	AuthClaimExpiredAt string
+	AuthClaimUnixTimes bool
+
+	// Answers of user_service's VerifyToken are cached for up to
+	// AuthCacheTTL, never past the token's expiry; tokens without a
+	// readable expiry aren't cached. A size of 0 disables the cache.
+	AuthCacheSize int
+	AuthCacheTTL  time.Duration
+
+	// Access tokens issued by the gateway on refresh live for
+	// AccessTokenTTL, refresh tokens for RefreshTokenTTL.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// StoreBackend keeps refresh tokens, revoked tokens and failed login
+	// attempts. It is "memory", which does not share state between
+	// instances, or "redis" to use the server at RedisAddr.
+	StoreBackend  string
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+
+	// Failed attempts to log in or to enter a verification code are
+	// counted per email and per client IP over BruteForceWindow. After the
+	// first failure every further one makes the email wait
+	// BruteForceBaseDelay, doubled each time, before trying again; once
+	// BruteForceEmailMaxAttempts (or BruteForceIPMaxAttempts for the IP)
+	// is reached it is locked out for BruteForceLockout. Every request for
+	// a password reset email counts as an attempt. Max attempts of 0
+	// disable the protection.
+	BruteForceEmailMaxAttempts int
+	BruteForceIPMaxAttempts    int
+	BruteForceWindow           time.Duration
+	BruteForceBaseDelay        time.Duration
+	BruteForceLockout          time.Duration
+
+	// ShutdownTimeout is how long in-flight requests are given to finish
+	// after SIGTERM/SIGINT before the server is closed forcibly.
+	ShutdownTimeout time.Duration
+
+	// Deadlines applied to every request of a route group, including
+	// the token verification done by AuthMiddleware.
+	AuthTimeout     time.Duration
+	UserTimeout     time.Duration
+	PostTimeout     time.Duration
+	CommentTimeout  time.Duration
+	LikeTimeout     time.Duration
+	CategoryTimeout time.Duration
+
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies in
+	// front of the gateway. Only requests coming from them may name the
+	// client IP in X-Forwarded-For or X-Real-IP; everyone else is known by
+	// the address they connect from. None are trusted by default.
+	TrustedProxies []string
+
+	// Each client may make up to the group's rate limit requests per
+	// RateLimitPeriod to a route group, refilled continuously. Clients are
+	// told apart by user id when their token can be verified locally and
+	// by IP otherwise. A limit of 0 disables limiting for the group.
+	RateLimitPeriod   time.Duration
+	AuthRateLimit     int
+	UserRateLimit     int
+	PostRateLimit     int
+	CommentRateLimit  int
+	LikeRateLimit     int
+	CategoryRateLimit int
+
+	// Successful responses of the public category and post reads are
+	// cached for the route's TTL, in the shared store when that is Redis
+	// and otherwise in the process, up to ResponseCacheSize responses.
+	// Changes made through the gateway invalidate them at once. Reading a
+	// post doesn't count a view (the gateway never calls ViewInc), so
+	// cached reads don't lose any. A TTL of 0 disables caching for the
+	// route.
+	ResponseCacheSize int
+	CategoryCacheTTL  time.Duration
+	PostListCacheTTL  time.Duration
+	PostCacheTTL      time.Duration
+
+	// HealthCheckTimeout bounds the backend checks done by /readyz.
+	HealthCheckTimeout time.Duration
+
+	// A backend's circuit breaker opens after BreakerFailureThreshold
+	// consecutive failures and fails every call fast for BreakerOpenTimeout.
+	// It then lets BreakerHalfOpenRequests probes through and closes once
+	// all of them succeed.
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+	BreakerHalfOpenRequests int
+
+	// Read-only rpcs failing with a transient error are tried up to
+	// RetryMaxAttempts times in total, backing off from RetryBaseDelay up
+	// to RetryMaxDelay between attempts.
+	RetryMaxAttempts int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+
+	// ServiceName is reported as service.name on every span.
+	ServiceName string
+	// TracingExporter is one of "none", "stdout" or "otlp".
+	TracingExporter    string
+	OtlpEndpoint       string
+	TracingSampleRatio float64
 }
 
 func Load(path string) Config {
@@ -20,14 +156,143 @@ func Load(path string) Config {
 	conf := viper.New()
 	conf.AutomaticEnv()
 
+	conf.SetDefault("METRICS_PORT", ":9090")
+	conf.SetDefault("AUTH_VERIFY_MODE", AuthVerifyRemote)
+	conf.SetDefault("AUTH_REMOTE_FALLBACK", false)
+	conf.SetDefault("AUTH_CLAIM_ID", "id")
+	conf.SetDefault("AUTH_CLAIM_USER_ID", "user_id")
+	conf.SetDefault("AUTH_CLAIM_EMAIL", "email")
+	conf.SetDefault("AUTH_CLAIM_USER_TYPE", "user_type")
+	conf.SetDefault("AUTH_CLAIM_ISSUED_AT", "issued_at")
+	conf.SetDefault("AUTH_CLAIM_EXPIRED_AT", "expired_at")
+	conf.SetDefault("AUTH_CLAIM_UNIX_TIMES", false)
+	conf.SetDefault("AUTH_CACHE_SIZE", 10000)
+	conf.SetDefault("AUTH_CACHE_TTL", time.Minute)
+	conf.SetDefault("ACCESS_TOKEN_TTL", time.Hour)
+	conf.SetDefault("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	conf.SetDefault("STORE_BACKEND", "memory")
+	conf.SetDefault("REDIS_ADDR", "localhost:6379")
+	conf.SetDefault("REDIS_DB", 0)
+	conf.SetDefault("BRUTE_FORCE_EMAIL_MAX_ATTEMPTS", 5)
+	conf.SetDefault("BRUTE_FORCE_IP_MAX_ATTEMPTS", 20)
+	conf.SetDefault("BRUTE_FORCE_WINDOW", 15*time.Minute)
+	conf.SetDefault("BRUTE_FORCE_BASE_DELAY", time.Second)
+	conf.SetDefault("BRUTE_FORCE_LOCKOUT", 15*time.Minute)
+	conf.SetDefault("SHUTDOWN_TIMEOUT", 15*time.Second)
+	conf.SetDefault("AUTH_TIMEOUT", 10*time.Second)
+	conf.SetDefault("USER_TIMEOUT", 5*time.Second)
+	conf.SetDefault("POST_TIMEOUT", 5*time.Second)
+	conf.SetDefault("COMMENT_TIMEOUT", 5*time.Second)
+	conf.SetDefault("LIKE_TIMEOUT", 5*time.Second)
+	conf.SetDefault("CATEGORY_TIMEOUT", 5*time.Second)
+	conf.SetDefault("RATE_LIMIT_PERIOD", time.Minute)
+	conf.SetDefault("AUTH_RATE_LIMIT", 30)
+	conf.SetDefault("USER_RATE_LIMIT", 120)
+	conf.SetDefault("POST_RATE_LIMIT", 120)
+	conf.SetDefault("COMMENT_RATE_LIMIT", 60)
+	conf.SetDefault("LIKE_RATE_LIMIT", 60)
+	conf.SetDefault("CATEGORY_RATE_LIMIT", 120)
+	conf.SetDefault("RESPONSE_CACHE_SIZE", 1000)
+	conf.SetDefault("CATEGORY_CACHE_TTL", 5*time.Minute)
+	conf.SetDefault("POST_LIST_CACHE_TTL", 30*time.Second)
+	conf.SetDefault("POST_CACHE_TTL", time.Minute)
+	conf.SetDefault("HEALTH_CHECK_TIMEOUT", 2*time.Second)
+	conf.SetDefault("BREAKER_FAILURE_THRESHOLD", 5)
+	conf.SetDefault("BREAKER_OPEN_TIMEOUT", 30*time.Second)
+	conf.SetDefault("BREAKER_HALF_OPEN_REQUESTS", 1)
+	conf.SetDefault("RETRY_MAX_ATTEMPTS", 3)
+	conf.SetDefault("RETRY_BASE_DELAY", 50*time.Millisecond)
+	conf.SetDefault("RETRY_MAX_DELAY", time.Second)
+	conf.SetDefault("SERVICE_NAME", "medium_api_gateway")
+	conf.SetDefault("TRACING_EXPORTER", "none")
+	conf.SetDefault("OTLP_ENDPOINT", "localhost:4317")
+	conf.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+
 	cfg := Config{
 		HttpPort:            conf.GetString("HTTP_PORT"),
+		MetricsPort:         conf.GetString("METRICS_PORT"),
 		UserServiceHost:     conf.GetString("USER_SERVICE_HOST"),
 		UserServiceGrpcPort: conf.GetString("USER_SERVICE_GRPC_PORT"),
 		PostServiceHost:     conf.GetString("POST_SERVICE_HOST"),
 		PostServiceGrpcPort: conf.GetString("POST_SERVICE_GRPC_PORT"),
 		AuthSecretKey:       conf.GetString("AUTH_SECRET_KEY"),
+
+		AuthVerifyMode:     conf.GetString("AUTH_VERIFY_MODE"),
+		AuthPolicyPath:     conf.GetString("AUTH_POLICY_PATH"),
+		AuthRemoteFallback: conf.GetBool("AUTH_REMOTE_FALLBACK"),
+		AuthClaimID:        conf.GetString("AUTH_CLAIM_ID"),
+		AuthClaimUserID:    conf.GetString("AUTH_CLAIM_USER_ID"),
+		AuthClaimEmail:     conf.GetString("AUTH_CLAIM_EMAIL"),
+		AuthClaimUserType:  conf.GetString("AUTH_CLAIM_USER_TYPE"),
+		AuthClaimIssuedAt:  conf.GetString("AUTH_CLAIM_ISSUED_AT"),
+		AuthClaimExpiredAt: conf.GetString("AUTH_CLAIM_EXPIRED_AT"),
+		AuthClaimUnixTimes: conf.GetBool("AUTH_CLAIM_UNIX_TIMES"),
+		AuthCacheSize:      conf.GetInt("AUTH_CACHE_SIZE"),
+		AuthCacheTTL:       conf.GetDuration("AUTH_CACHE_TTL"),
+
+		AccessTokenTTL:  conf.GetDuration("ACCESS_TOKEN_TTL"),
+		RefreshTokenTTL: conf.GetDuration("REFRESH_TOKEN_TTL"),
+		StoreBackend:    conf.GetString("STORE_BACKEND"),
+		RedisAddr:       conf.GetString("REDIS_ADDR"),
+		RedisPassword:   conf.GetString("REDIS_PASSWORD"),
+		RedisDB:         conf.GetInt("REDIS_DB"),
+
+		BruteForceEmailMaxAttempts: conf.GetInt("BRUTE_FORCE_EMAIL_MAX_ATTEMPTS"),
+		BruteForceIPMaxAttempts:    conf.GetInt("BRUTE_FORCE_IP_MAX_ATTEMPTS"),
+		BruteForceWindow:           conf.GetDuration("BRUTE_FORCE_WINDOW"),
+		BruteForceBaseDelay:        conf.GetDuration("BRUTE_FORCE_BASE_DELAY"),
+		BruteForceLockout:          conf.GetDuration("BRUTE_FORCE_LOCKOUT"),
+
+		ShutdownTimeout: conf.GetDuration("SHUTDOWN_TIMEOUT"),
+
+		AuthTimeout:     conf.GetDuration("AUTH_TIMEOUT"),
+		UserTimeout:     conf.GetDuration("USER_TIMEOUT"),
+		PostTimeout:     conf.GetDuration("POST_TIMEOUT"),
+		CommentTimeout:  conf.GetDuration("COMMENT_TIMEOUT"),
+		LikeTimeout:     conf.GetDuration("LIKE_TIMEOUT"),
+		CategoryTimeout: conf.GetDuration("CATEGORY_TIMEOUT"),
+
+		TrustedProxies: splitList(conf.GetString("TRUSTED_PROXIES")),
+
+		RateLimitPeriod:   conf.GetDuration("RATE_LIMIT_PERIOD"),
+		AuthRateLimit:     conf.GetInt("AUTH_RATE_LIMIT"),
+		UserRateLimit:     conf.GetInt("USER_RATE_LIMIT"),
+		PostRateLimit:     conf.GetInt("POST_RATE_LIMIT"),
+		CommentRateLimit:  conf.GetInt("COMMENT_RATE_LIMIT"),
+		LikeRateLimit:     conf.GetInt("LIKE_RATE_LIMIT"),
+		CategoryRateLimit: conf.GetInt("CATEGORY_RATE_LIMIT"),
+
+		ResponseCacheSize: conf.GetInt("RESPONSE_CACHE_SIZE"),
+		CategoryCacheTTL:  conf.GetDuration("CATEGORY_CACHE_TTL"),
+		PostListCacheTTL:  conf.GetDuration("POST_LIST_CACHE_TTL"),
+		PostCacheTTL:      conf.GetDuration("POST_CACHE_TTL"),
+
+		HealthCheckTimeout: conf.GetDuration("HEALTH_CHECK_TIMEOUT"),
+
+		BreakerFailureThreshold: conf.GetInt("BREAKER_FAILURE_THRESHOLD"),
+		BreakerOpenTimeout:      conf.GetDuration("BREAKER_OPEN_TIMEOUT"),
+		BreakerHalfOpenRequests: conf.GetInt("BREAKER_HALF_OPEN_REQUESTS"),
+
+		RetryMaxAttempts: conf.GetInt("RETRY_MAX_ATTEMPTS"),
+		RetryBaseDelay:   conf.GetDuration("RETRY_BASE_DELAY"),
+		RetryMaxDelay:    conf.GetDuration("RETRY_MAX_DELAY"),
+
+		ServiceName:        conf.GetString("SERVICE_NAME"),
+		TracingExporter:    conf.GetString("TRACING_EXPORTER"),
+		OtlpEndpoint:       conf.GetString("OTLP_ENDPOINT"),
+		TracingSampleRatio: conf.GetFloat64("TRACING_SAMPLE_RATIO"),
 	}
 
 	return cfg
 }
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
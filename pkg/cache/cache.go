@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+)
+
+const (
+	sharedValuePrefix      = "cache:value:"
+	sharedGenerationPrefix = "cache:generation:"
+)
+
+// Cache keeps byte values in namespaces. Invalidating a namespace bumps its
+// generation; callers put the generation into their keys, so everything
+// cached before is never looked up again and simply ages out.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Generation(ctx context.Context, namespace string) (int64, error)
+	Invalidate(ctx context.Context, namespace string) error
+}
+
+// New returns a cache next to the rest of the shared state: in Redis when
+// s is kept there, so every instance sees the same entries and
+// invalidations, and in the process, holding up to size entries,
+// otherwise.
+func New(size int, s store.Store) Cache {
+	if r, ok := s.(*store.Redis); ok {
+		return NewShared(r)
+	}
+	return NewLocal(size)
+}
+
+// Local is a Cache kept in the process.
+type Local struct {
+	values *LRU[string, []byte]
+
+	mu          sync.Mutex
+	generations map[string]int64
+}
+
+func NewLocal(size int) *Local {
+	return &Local{
+		values:      NewLRU[string, []byte](size),
+		generations: make(map[string]int64),
+	}
+}
+
+func (l *Local) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := l.values.Get(key)
+	return value, ok, nil
+}
+
+func (l *Local) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	l.values.Set(key, value, ttl)
+	return nil
+}
+
+func (l *Local) Generation(_ context.Context, namespace string) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.generations[namespace], nil
+}
+
+func (l *Local) Invalidate(_ context.Context, namespace string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.generations[namespace]++
+	return nil
+}
+
+// Shared is a Cache kept in a Store shared by every gateway instance.
+type Shared struct {
+	store store.Store
+}
+
+func NewShared(s store.Store) *Shared {
+	return &Shared{store: s}
+}
+
+func (s *Shared) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.store.Get(ctx, sharedValuePrefix+key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return []byte(value), true, nil
+}
+
+func (s *Shared) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return s.store.Set(ctx, sharedValuePrefix+key, string(value), ttl)
+}
+
+func (s *Shared) Generation(ctx context.Context, namespace string) (int64, error) {
+	value, err := s.store.Get(ctx, sharedGenerationPrefix+namespace)
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func (s *Shared) Invalidate(ctx context.Context, namespace string) error {
+	_, err := s.store.Incr(ctx, sharedGenerationPrefix+namespace, 0)
+	return err
+}
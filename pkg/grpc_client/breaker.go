@@ -0,0 +1,219 @@
+package grpc_client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	errorDomain       = "api_gateway"
+	reasonCircuitOpen = "CIRCUIT_OPEN"
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// BreakerStatus is a snapshot of the circuit breaker of one backend.
+type BreakerStatus struct {
+	Name     string
+	State    BreakerState
+	Failures int
+	// RetryAfter is how long the breaker stays open before letting probes
+	// through. It is zero unless the breaker is open.
+	RetryAfter time.Duration
+}
+
+// breaker stops calls to a backend after FailureThreshold consecutive
+// failures. Once OpenTimeout has passed it lets up to HalfOpenRequests
+// probes through; the breaker closes when all of them succeed and opens
+// again as soon as one fails.
+type breaker struct {
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenRequests int
+	// now tells the time; tests replace it to move the clock.
+	now func() time.Time
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+func newBreaker(name string, failureThreshold int, openTimeout time.Duration, halfOpenRequests int) *breaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	if halfOpenRequests < 1 {
+		halfOpenRequests = 1
+	}
+
+	b := &breaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		halfOpenRequests: halfOpenRequests,
+		now:              time.Now,
+	}
+	metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(BreakerClosed))
+	return b
+}
+
+// allow reports whether a call may go through. When it may, done must be
+// called with the call's outcome.
+func (b *breaker) allow() (done func(err error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if wait := b.openTimeout - b.now().Sub(b.openedAt); wait > 0 {
+			return nil, b.openError(wait)
+		}
+		b.setState(BreakerHalfOpen)
+	}
+
+	if b.state == BreakerHalfOpen {
+		if b.probes >= b.halfOpenRequests {
+			return nil, b.openError(time.Second)
+		}
+		b.probes++
+	}
+
+	return b.done, nil
+}
+
+func (b *breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := isBackendFailure(err)
+
+	switch b.state {
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.open()
+		}
+	case BreakerHalfOpen:
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenRequests {
+			b.failures = 0
+			b.setState(BreakerClosed)
+		}
+	}
+}
+
+func (b *breaker) open() {
+	b.openedAt = b.now()
+	b.setState(BreakerOpen)
+}
+
+func (b *breaker) setState(state BreakerState) {
+	b.state = state
+	b.probes = 0
+	b.successes = 0
+	metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(state))
+}
+
+func (b *breaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := BreakerStatus{
+		Name:     b.name,
+		State:    b.state,
+		Failures: b.failures,
+	}
+	if b.state == BreakerOpen {
+		if wait := b.openTimeout - b.now().Sub(b.openedAt); wait > 0 {
+			s.RetryAfter = wait
+		}
+	}
+	return s
+}
+
+func (b *breaker) openError(retryAfter time.Duration) error {
+	st, err := status.New(codes.Unavailable, fmt.Sprintf("circuit breaker for %s is open", b.name)).
+		WithDetails(
+			&errdetails.ErrorInfo{Reason: reasonCircuitOpen, Domain: errorDomain},
+			&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)},
+		)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "circuit breaker for %s is open", b.name)
+	}
+	return st.Err()
+}
+
+func (b *breaker) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := b.allow()
+		if err != nil {
+			return err
+		}
+
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(err)
+		return err
+	}
+}
+
+// isBackendFailure tells errors caused by an unhealthy backend apart from
+// ordinary application errors such as NotFound, which must not trip the
+// breaker.
+func isBackendFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// IsCircuitOpen reports whether err was returned without calling the
+// backend because its circuit breaker is open.
+func IsCircuitOpen(err error) bool {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok &&
+			info.GetDomain() == errorDomain && info.GetReason() == reasonCircuitOpen {
+			return true
+		}
+	}
+	return false
+}
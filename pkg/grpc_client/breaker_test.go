@@ -0,0 +1,128 @@
+package grpc_client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBreaker(failureThreshold, halfOpenRequests int) (*breaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2022, 12, 26, 0, 0, 0, 0, time.UTC)}
+	b := newBreaker("test", failureThreshold, time.Minute, halfOpenRequests)
+	b.now = clock.now
+	return b, clock
+}
+
+var errUnavailable = status.Error(codes.Unavailable, "down")
+
+// call makes a call through b to a backend answering with err, and tells
+// whether the backend was reached.
+func call(t *testing.T, b *breaker, err error) (reached bool) {
+	t.Helper()
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		reached = true
+		return err
+	}
+	got := b.unaryClientInterceptor()(context.Background(), "/genproto.PostService/Get", nil, nil, nil, invoker)
+	if !reached && !IsCircuitOpen(got) {
+		t.Fatalf("rejected call failed with %v, want a circuit open error", got)
+	}
+	return reached
+}
+
+func TestBreakerOpensAtThreshold(t *testing.T) {
+	b, _ := newTestBreaker(3, 1)
+
+	// Application errors and successes in between don't add up.
+	call(t, b, errUnavailable)
+	call(t, b, status.Error(codes.NotFound, "no such post"))
+	call(t, b, errUnavailable)
+	call(t, b, nil)
+	if s := b.status(); s.State != BreakerClosed || s.Failures != 0 {
+		t.Fatalf("after a success: state %s with %d failures, want closed with 0", s.State, s.Failures)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if !call(t, b, errUnavailable) {
+			t.Fatalf("failure %d didn't reach the backend", i)
+		}
+	}
+	if s := b.status(); s.State != BreakerOpen || s.RetryAfter != time.Minute {
+		t.Fatalf("after 3 failures: state %s, retry after %s; want open for 1m0s", s.State, s.RetryAfter)
+	}
+}
+
+func TestBreakerRejectsWhileOpen(t *testing.T) {
+	b, clock := newTestBreaker(1, 1)
+	call(t, b, errUnavailable)
+
+	var elapsed time.Duration
+	for _, d := range []time.Duration{0, 30 * time.Second, 29 * time.Second} {
+		clock.advance(d)
+		elapsed += d
+		if call(t, b, nil) {
+			t.Fatalf("call %s after opening reached the backend", elapsed)
+		}
+	}
+	if s := b.status(); s.State != BreakerOpen || s.RetryAfter != time.Second {
+		t.Errorf("state %s, retry after %s; want open for 1s", s.State, s.RetryAfter)
+	}
+}
+
+func TestBreakerLetsOneProbeThrough(t *testing.T) {
+	b, clock := newTestBreaker(1, 1)
+	call(t, b, errUnavailable)
+	clock.advance(time.Minute)
+
+	done, err := b.allow()
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if s := b.status(); s.State != BreakerHalfOpen {
+		t.Fatalf("state %s, want half_open", s.State)
+	}
+	// Only one probe at a time.
+	if call(t, b, nil) {
+		t.Fatal("second call reached the backend while the probe is in flight")
+	}
+
+	done(nil)
+	if s := b.status(); s.State != BreakerClosed {
+		t.Fatalf("after the probe succeeded: state %s, want closed", s.State)
+	}
+	if !call(t, b, nil) {
+		t.Fatal("closed breaker rejected a call")
+	}
+}
+
+func TestBreakerReopensWhenProbeFails(t *testing.T) {
+	b, clock := newTestBreaker(1, 2)
+	call(t, b, errUnavailable)
+	clock.advance(time.Minute)
+
+	if !call(t, b, nil) {
+		t.Fatal("first probe rejected")
+	}
+	if !call(t, b, errUnavailable) {
+		t.Fatal("second probe rejected")
+	}
+	if s := b.status(); s.State != BreakerOpen || s.RetryAfter != time.Minute {
+		t.Fatalf("after a failed probe: state %s, retry after %s; want open for 1m0s", s.State, s.RetryAfter)
+	}
+	if call(t, b, nil) {
+		t.Fatal("reopened breaker let a call through")
+	}
+}
@@ -1,11 +1,14 @@
 package grpc_client
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/samandar2605/medium_api_gateway/config"
 	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
 	pbu "github.com/samandar2605/medium_api_gateway/genproto/user_service"
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -17,17 +20,39 @@ type GrpcClientI interface {
 	CategoryService() pbp.CategoryServiceClient
 	LikeService() pbp.LikeServiceClient
 	CommentService() pbp.CommentServiceClient
+	CheckHealth(ctx context.Context) []BackendHealth
+	BreakerStatus() []BreakerStatus
+	Close() error
 }
 
 type GrpcClient struct {
 	cfg         config.Config
+	backends    []backend
 	connections map[string]interface{}
 }
 
+type backend struct {
+	name    string
+	conn    *grpc.ClientConn
+	breaker *breaker
+}
+
 func New(cfg config.Config) (GrpcClientI, error) {
+	userBreaker := newBreaker("user_service", cfg.BreakerFailureThreshold,
+		cfg.BreakerOpenTimeout, cfg.BreakerHalfOpenRequests)
+	postBreaker := newBreaker("post_service", cfg.BreakerFailureThreshold,
+		cfg.BreakerOpenTimeout, cfg.BreakerHalfOpenRequests)
+
 	connUserService, err := grpc.Dial(
 		fmt.Sprintf("%s%s", cfg.UserServiceHost, cfg.UserServiceGrpcPort),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			otelgrpc.UnaryClientInterceptor(),
+			newCoalescer("user_service").unaryClientInterceptor(),
+			newRetryPolicy(cfg, "user_service").unaryClientInterceptor(),
+			metrics.UnaryClientInterceptor("user_service"),
+			userBreaker.unaryClientInterceptor(),
+		),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("user service dial host: %s port:%s err: %v",
@@ -37,14 +62,26 @@ func New(cfg config.Config) (GrpcClientI, error) {
 	connPostService, err := grpc.Dial(
 		fmt.Sprintf("%s%s", cfg.PostServiceHost, cfg.PostServiceGrpcPort),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			otelgrpc.UnaryClientInterceptor(),
+			newCoalescer("post_service").unaryClientInterceptor(),
+			newRetryPolicy(cfg, "post_service").unaryClientInterceptor(),
+			metrics.UnaryClientInterceptor("post_service"),
+			postBreaker.unaryClientInterceptor(),
+		),
 	)
 	if err != nil {
+		connUserService.Close()
 		return nil, fmt.Errorf("post service dial host: %s port:%s err: %v",
 			cfg.PostServiceHost, cfg.PostServiceGrpcPort, err)
 	}
 
 	return &GrpcClient{
 		cfg: cfg,
+		backends: []backend{
+			{name: "user_service", conn: connUserService, breaker: userBreaker},
+			{name: "post_service", conn: connPostService, breaker: postBreaker},
+		},
 		connections: map[string]interface{}{
 			"user_service":     pbu.NewUserServiceClient(connUserService),
 			"auth_service":     pbu.NewAuthServiceClient(connUserService),
@@ -56,6 +93,15 @@ func New(cfg config.Config) (GrpcClientI, error) {
 	}, nil
 }
 
+func newRetryPolicy(cfg config.Config, backend string) retryPolicy {
+	return retryPolicy{
+		backend:     backend,
+		maxAttempts: cfg.RetryMaxAttempts,
+		baseDelay:   cfg.RetryBaseDelay,
+		maxDelay:    cfg.RetryMaxDelay,
+	}
+}
+
 func (g *GrpcClient) UserService() pbu.UserServiceClient {
 	return g.connections["user_service"].(pbu.UserServiceClient)
 }
@@ -78,3 +124,24 @@ func (g *GrpcClient) LikeService() pbp.LikeServiceClient {
 func (g *GrpcClient) CommentService() pbp.CommentServiceClient {
 	return g.connections["comment_service"].(pbp.CommentServiceClient)
 }
+
+// BreakerStatus returns the state of the circuit breaker of every backend.
+func (g *GrpcClient) BreakerStatus() []BreakerStatus {
+	result := make([]BreakerStatus, 0, len(g.backends))
+	for _, b := range g.backends {
+		result = append(result, b.breaker.status())
+	}
+	return result
+}
+
+// Close tears down every backend connection. It must be called only after
+// the HTTP server has stopped handing out requests.
+func (g *GrpcClient) Close() error {
+	var firstErr error
+	for _, b := range g.backends {
+		if err := b.conn.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close connection to %s: %v", b.name, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,95 @@
+package grpc_client
+
+import (
+	"context"
+
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type coalesceKey struct{}
+
+// WithCoalescing marks the rpcs made with ctx as ones that may share their
+// result with identical rpcs already in flight. Only reads listed in
+// idempotentMethods are ever coalesced.
+func WithCoalescing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, coalesceKey{}, true)
+}
+
+func coalescingEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(coalesceKey{}).(bool)
+	return enabled
+}
+
+// coalescer collapses concurrent identical reads to a backend into a
+// single rpc whose reply is copied to every caller.
+type coalescer struct {
+	backend string
+	group   singleflight.Group
+}
+
+func newCoalescer(backend string) *coalescer {
+	return &coalescer{backend: backend}
+}
+
+func (c *coalescer) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !coalescingEnabled(ctx) || !idempotentMethods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		in, ok := req.(proto.Message)
+		out, ok2 := reply.(proto.Message)
+		if !ok || !ok2 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		// Deterministic, so equal requests always give equal keys.
+		key, err := proto.MarshalOptions{Deterministic: true}.Marshal(in)
+		if err != nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		leader := false
+		ch := c.group.DoChan(method+"\x00"+string(key), func() (interface{}, error) {
+			leader = true
+			result := out.ProtoReflect().New().Interface()
+			err := invoker(ctx, method, req, result, cc, opts...)
+			return result, err
+		})
+
+		var res singleflight.Result
+		select {
+		case res = <-ch:
+		case <-ctx.Done():
+			// Whoever started the call may keep it running for longer
+			// than this caller is willing to wait.
+			return status.FromContextError(ctx.Err()).Err()
+		}
+
+		result, err := res.Val, res.Err
+		if !leader {
+			service, name := metrics.SplitMethod(method)
+			metrics.GrpcClientCoalesced.WithLabelValues(c.backend, service, name).Inc()
+
+			// The call ran with the context of whoever started it. If
+			// that client went away or ran out of time, the others still
+			// want an answer.
+			code := status.Code(err)
+			if (code == codes.Canceled || code == codes.DeadlineExceeded) && ctx.Err() == nil {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		proto.Merge(out, result.(proto.Message))
+		return nil
+	}
+}
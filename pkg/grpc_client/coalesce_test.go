@@ -0,0 +1,92 @@
+package grpc_client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pbp "github.com/samandar2605/medium_api_gateway/genproto/post_service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const coalescedMethod = "/genproto.PostService/Get"
+
+// startLeader starts a coalesced call whose rpc blocks until release is
+// closed and then fails with err, and waits for it to be in flight.
+func startLeader(t *testing.T, c *coalescer, err error) (release chan struct{}) {
+	t.Helper()
+
+	release = make(chan struct{})
+	started := make(chan struct{})
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		close(started)
+		<-release
+		return err
+	}
+	go c.unaryClientInterceptor()(WithCoalescing(context.Background()), coalescedMethod,
+		&pbp.GetPostRequest{Id: 1}, &pbp.Post{}, nil, invoker)
+	<-started
+	return release
+}
+
+func TestCoalescerFollowerStopsWaitingOnItsDeadline(t *testing.T) {
+	c := newCoalescer("post_service")
+	release := startLeader(t, c, nil)
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(WithCoalescing(context.Background()), 20*time.Millisecond)
+	defer cancel()
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		t.Error("follower made its own rpc")
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.unaryClientInterceptor()(ctx, coalescedMethod, &pbp.GetPostRequest{Id: 1}, &pbp.Post{}, nil, invoker)
+	}()
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.DeadlineExceeded {
+			t.Errorf("err = %v, want code %s", err, codes.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("follower still waits for the leader after its deadline")
+	}
+}
+
+func TestCoalescerFollowerRetriesLeadersContextErrors(t *testing.T) {
+	for _, code := range []codes.Code{codes.Canceled, codes.DeadlineExceeded} {
+		c := newCoalescer("post_service")
+		release := startLeader(t, c, status.Error(code, "leader gone"))
+
+		calls := 0
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			reply.(*pbp.Post).Id = 1
+			return nil
+		}
+
+		done := make(chan error, 1)
+		reply := &pbp.Post{}
+		go func() {
+			done <- c.unaryClientInterceptor()(WithCoalescing(context.Background()), coalescedMethod,
+				&pbp.GetPostRequest{Id: 1}, reply, nil, invoker)
+		}()
+
+		// Give the follower time to join the leader's call.
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+
+		if err := <-done; err != nil {
+			t.Errorf("leader failed with %s: err = %v, want nil", code, err)
+		}
+		if calls != 1 || reply.Id != 1 {
+			t.Errorf("leader failed with %s: follower made %d rpcs, reply %v", code, calls, reply)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package grpc_client
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// BackendHealth is the outcome of checking a single backend service.
+type BackendHealth struct {
+	Name    string
+	State   connectivity.State
+	Serving bool
+	Err     error
+}
+
+// CheckHealth reports the connectivity state of every backend connection
+// and asks each backend for its status through the standard
+// grpc.health.v1 Health/Check rpc. The checks run concurrently and are
+// bounded by ctx.
+//
+// A backend that does not implement the health service is considered
+// serving as long as it answered at all.
+func (g *GrpcClient) CheckHealth(ctx context.Context) []BackendHealth {
+	result := make([]BackendHealth, len(g.backends))
+
+	var wg sync.WaitGroup
+	for i, b := range g.backends {
+		wg.Add(1)
+		go func(i int, b backend) {
+			defer wg.Done()
+			result[i] = checkBackend(ctx, b)
+		}(i, b)
+	}
+	wg.Wait()
+
+	return result
+}
+
+func checkBackend(ctx context.Context, b backend) BackendHealth {
+	// An idle connection would report its state as such until the first
+	// rpc, so make it start connecting right away.
+	if b.conn.GetState() == connectivity.Idle {
+		b.conn.Connect()
+	}
+
+	h := BackendHealth{Name: b.name}
+	resp, err := healthpb.NewHealthClient(b.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	h.State = b.conn.GetState()
+
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		h.Serving = true
+	case err != nil:
+		h.Err = err
+	default:
+		h.Serving = resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	}
+	return h
+}
@@ -0,0 +1,105 @@
+package grpc_client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/pkg/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// idempotentMethods lists the rpcs that only read data and can therefore
+// be retried safely. Anything not listed here, in particular Create,
+// Update, Delete, CreateOrUpdate and ViewInc, is never retried.
+var idempotentMethods = map[string]bool{
+	"/genproto.UserService/Get":                   true,
+	"/genproto.UserService/GetAll":                true,
+	"/genproto.UserService/GetByEmail":            true,
+	"/genproto.AuthService/VerifyToken":           true,
+	"/genproto.PostService/Get":                   true,
+	"/genproto.PostService/GetAll":                true,
+	"/genproto.CategoryService/Get":               true,
+	"/genproto.CategoryService/GetAll":            true,
+	"/genproto.CommentService/Get":                true,
+	"/genproto.CommentService/GetAll":             true,
+	"/genproto.LikeService/Get":                   true,
+	"/genproto.LikeService/GetLikesDislikesCount": true,
+}
+
+type retryPolicy struct {
+	backend     string
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// unaryClientInterceptor retries idempotent rpcs that failed with a
+// transient error, waiting an exponentially growing, jittered delay
+// between attempts. It gives up early when the next attempt could not
+// start before the request's deadline, and never retries a call that was
+// rejected by an open circuit breaker.
+func (p retryPolicy) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if p.maxAttempts <= 1 || !idempotentMethods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		service, name := metrics.SplitMethod(method)
+
+		var err error
+		for attempt := 0; attempt < p.maxAttempts; attempt++ {
+			if attempt > 0 {
+				delay := p.backoff(attempt)
+				if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+					return err
+				}
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				case <-timer.C:
+				}
+				metrics.GrpcClientRetries.WithLabelValues(p.backend, service, name).Inc()
+			}
+
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			if !retryable(err) {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+// backoff returns a random delay between zero and baseDelay*2^(attempt-1),
+// capped at maxDelay ("full jitter").
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.maxDelay
+	if shift := attempt - 1; shift < 30 {
+		if d := p.baseDelay << shift; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func retryable(err error) bool {
+	if err == nil || IsCircuitOpen(err) {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
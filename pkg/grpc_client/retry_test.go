@@ -0,0 +1,170 @@
+package grpc_client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// countingInvoker fails every call with the next of errs, or the last one
+// once they run out, and counts the calls.
+func countingInvoker(calls *int, errs ...error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return errs[len(errs)-1]
+	}
+}
+
+var testRetryPolicy = retryPolicy{
+	backend:     "test",
+	maxAttempts: 3,
+	baseDelay:   time.Millisecond,
+	maxDelay:    time.Millisecond,
+}
+
+func TestRetryNeverRetriesMutatingMethods(t *testing.T) {
+	methods := []string{
+		"/genproto.UserService/Create",
+		"/genproto.UserService/Update",
+		"/genproto.UserService/Delete",
+		"/genproto.PostService/Create",
+		"/genproto.PostService/Update",
+		"/genproto.PostService/Delete",
+		"/genproto.PostService/ViewInc",
+		"/genproto.CommentService/Create",
+		"/genproto.CommentService/Update",
+		"/genproto.CommentService/Delete",
+		"/genproto.CategoryService/Create",
+		"/genproto.CategoryService/Update",
+		"/genproto.CategoryService/Delete",
+		"/genproto.LikeService/CreateOrUpdate",
+		"/genproto.AuthService/Register",
+		"/genproto.AuthService/Verify",
+		"/genproto.AuthService/Login",
+		"/genproto.AuthService/ForgotPassword",
+		"/genproto.AuthService/VerifyForgotPassword",
+		"/genproto.AuthService/UpdatePassword",
+		"/genproto.NotificationService/SendEmail",
+	}
+	for _, method := range methods {
+		calls := 0
+		err := testRetryPolicy.unaryClientInterceptor()(context.Background(), method, nil, nil, nil,
+			countingInvoker(&calls, status.Error(codes.Unavailable, "down")))
+		if status.Code(err) != codes.Unavailable || calls != 1 {
+			t.Errorf("%s: %d calls, err %v; want 1 call", method, calls, err)
+		}
+	}
+}
+
+func TestRetryCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantCode  codes.Code
+	}{
+		{"ok", []error{nil}, 1, codes.OK},
+		{"recovers", []error{status.Error(codes.Unavailable, "down"), nil}, 2, codes.OK},
+		{"unavailable", []error{status.Error(codes.Unavailable, "down")}, 3, codes.Unavailable},
+		{"resource exhausted", []error{status.Error(codes.ResourceExhausted, "busy")}, 3, codes.ResourceExhausted},
+		{"not found", []error{status.Error(codes.NotFound, "no such post")}, 1, codes.NotFound},
+		{"invalid argument", []error{status.Error(codes.InvalidArgument, "bad id")}, 1, codes.InvalidArgument},
+		{"internal", []error{status.Error(codes.Internal, "bug")}, 1, codes.Internal},
+		{"deadline exceeded", []error{status.Error(codes.DeadlineExceeded, "slow")}, 1, codes.DeadlineExceeded},
+		{"canceled", []error{status.Error(codes.Canceled, "gone")}, 1, codes.Canceled},
+		{"circuit open", []error{newBreaker("test", 1, time.Minute, 1).openError(time.Second)}, 1, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		calls := 0
+		err := testRetryPolicy.unaryClientInterceptor()(context.Background(), "/genproto.PostService/Get", nil, nil, nil,
+			countingInvoker(&calls, tt.errs...))
+		if calls != tt.wantCalls || status.Code(err) != tt.wantCode {
+			t.Errorf("%s: %d calls, err %v; want %d calls, code %s", tt.name, calls, err, tt.wantCalls, tt.wantCode)
+		}
+	}
+}
+
+func TestRetryAttemptCap(t *testing.T) {
+	for _, maxAttempts := range []int{0, 1, 2, 5} {
+		p := testRetryPolicy
+		p.maxAttempts = maxAttempts
+
+		calls := 0
+		p.unaryClientInterceptor()(context.Background(), "/genproto.PostService/Get", nil, nil, nil,
+			countingInvoker(&calls, status.Error(codes.Unavailable, "down")))
+
+		want := maxAttempts
+		if want < 1 {
+			want = 1
+		}
+		if calls != want {
+			t.Errorf("max attempts %d: %d calls, want %d", maxAttempts, calls, want)
+		}
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	// Every backoff is far longer than the caller is willing to wait.
+	p := testRetryPolicy
+	p.baseDelay, p.maxDelay = time.Hour, time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := p.unaryClientInterceptor()(ctx, "/genproto.PostService/Get", nil, nil, nil,
+		countingInvoker(&calls, status.Error(codes.Unavailable, "down")))
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("gave up after %s, want before the deadline", elapsed)
+	}
+	if calls != 1 || status.Code(err) != codes.Unavailable {
+		t.Errorf("%d calls, err %v; want 1 call failing with the backend's error", calls, err)
+	}
+}
+
+func TestRetryStopsWhenCanceled(t *testing.T) {
+	p := testRetryPolicy
+	p.baseDelay, p.maxDelay = time.Hour, time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- p.unaryClientInterceptor()(ctx, "/genproto.PostService/Get", nil, nil, nil,
+			countingInvoker(&calls, status.Error(codes.Unavailable, "down")))
+	}()
+
+	select {
+	case err := <-done:
+		if calls != 1 || status.Code(err) != codes.Unavailable {
+			t.Errorf("%d calls, err %v; want 1 call failing with the backend's error", calls, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("still backing off after the caller went away")
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	p := retryPolicy{baseDelay: 10 * time.Millisecond, maxDelay: 50 * time.Millisecond}
+	for attempt := 1; attempt <= 40; attempt++ {
+		ceiling := p.maxDelay
+		if attempt <= 3 {
+			ceiling = p.baseDelay << (attempt - 1)
+		}
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(attempt); d < 0 || d > ceiling {
+				t.Fatalf("attempt %d: backoff %s, want at most %s", attempt, d, ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// DefaultLanguage is used when none of the languages a client accepts is
+// supported, and is the reference every other catalog is checked against.
+const DefaultLanguage = "en"
+
+// Languages lists the supported catalogs, DefaultLanguage first.
+var Languages = []string{DefaultLanguage, "ru", "uz"}
+
+type Catalog struct {
+	messages map[string]map[string]string
+	matcher  language.Matcher
+}
+
+// Load reads the embedded message catalogs. It fails when a catalog is
+// missing a key of the default catalog or has keys the default one lacks,
+// so an incomplete translation is caught at startup.
+func Load() (*Catalog, error) {
+	c := &Catalog{
+		messages: make(map[string]map[string]string, len(Languages)),
+	}
+
+	tags := make([]language.Tag, 0, len(Languages))
+	for _, lang := range Languages {
+		data, err := locales.ReadFile("locales/" + lang + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s catalog: %v", lang, err)
+		}
+
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse %s catalog: %v", lang, err)
+		}
+
+		c.messages[lang] = messages
+		tags = append(tags, language.Make(lang))
+	}
+	c.matcher = language.NewMatcher(tags)
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Catalog) validate() error {
+	reference := c.messages[DefaultLanguage]
+	for _, lang := range Languages[1:] {
+		var missing, extra []string
+		for key := range reference {
+			if _, ok := c.messages[lang][key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		for key := range c.messages[lang] {
+			if _, ok := reference[key]; !ok {
+				extra = append(extra, key)
+			}
+		}
+
+		if len(missing) > 0 || len(extra) > 0 {
+			sort.Strings(missing)
+			sort.Strings(extra)
+			return fmt.Errorf("%s catalog is out of sync with %s: missing %v, unknown %v",
+				lang, DefaultLanguage, missing, extra)
+		}
+	}
+	return nil
+}
+
+// Localizer picks the catalog that best matches an Accept-Language header.
+func (c *Catalog) Localizer(acceptLanguage string) *Localizer {
+	lang := DefaultLanguage
+	if tags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil && len(tags) > 0 {
+		_, index, confidence := c.matcher.Match(tags...)
+		if confidence != language.No {
+			lang = Languages[index]
+		}
+	}
+
+	return &Localizer{
+		lang:     lang,
+		messages: c.messages[lang],
+	}
+}
+
+// Localizer translates messages into a single language. A nil Localizer
+// is valid and always returns the fallback message.
+type Localizer struct {
+	lang     string
+	messages map[string]string
+}
+
+func (l *Localizer) Language() string {
+	if l == nil {
+		return DefaultLanguage
+	}
+	return l.lang
+}
+
+// Translate returns the message stored under key with every {name}
+// placeholder replaced from params, or fallback when the key is unknown.
+func (l *Localizer) Translate(key, fallback string, params map[string]string) string {
+	if l == nil {
+		return fallback
+	}
+
+	message, ok := l.messages[key]
+	if !ok {
+		return fallback
+	}
+
+	if len(params) == 0 {
+		return message
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
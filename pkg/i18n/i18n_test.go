@@ -0,0 +1,76 @@
+package i18n_test
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"testing"
+
+	v1 "github.com/samandar2605/medium_api_gateway/api/v1"
+	"github.com/samandar2605/medium_api_gateway/pkg/i18n"
+)
+
+func catalogKeys(t *testing.T, lang string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile("locales/" + lang + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(map[string]string)
+	if err := json.Unmarshal(data, &messages); err != nil {
+		t.Fatalf("%s: %v", lang, err)
+	}
+
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	if _, err := i18n.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	reference := catalogKeys(t, i18n.DefaultLanguage)
+	for _, lang := range i18n.Languages[1:] {
+		keys := catalogKeys(t, lang)
+
+		want := make(map[string]bool, len(reference))
+		for _, key := range reference {
+			want[key] = true
+		}
+		for _, key := range keys {
+			if !want[key] {
+				t.Errorf("%s has %q, %s doesn't", lang, key, i18n.DefaultLanguage)
+			}
+			delete(want, key)
+		}
+		for key := range want {
+			t.Errorf("%s is missing %q", lang, key)
+		}
+	}
+}
+
+func TestCatalogsCoverGatewayMessages(t *testing.T) {
+	catalog, err := i18n.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lang := range i18n.Languages {
+		l := catalog.Localizer(lang)
+		if l.Language() != lang {
+			t.Fatalf("Localizer(%q) picked %q", lang, l.Language())
+		}
+
+		for _, key := range v1.MessageKeys() {
+			if l.Translate(key, "", nil) == "" {
+				t.Errorf("%s has no message for %q", lang, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "api_gateway"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being handled, by route template and method.",
+	}, []string{"route", "method"})
+
+	GrpcClientHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_handled_total",
+		Help:      "RPCs completed against the backends, by backend, service, method and status code.",
+	}, []string{"backend", "service", "method", "code"})
+
+	GrpcClientDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_handling_seconds",
+		Help:      "RPC latency against the backends, by backend, service, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "service", "method", "code"})
+
+	GrpcClientRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_retries_total",
+		Help:      "RPCs retried after a transient failure, by backend, service and method.",
+	}, []string{"backend", "service", "method"})
+
+	GrpcClientCoalesced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_coalesced_total",
+		Help:      "RPCs answered with the result of an identical rpc already in flight, by backend, service and method.",
+	}, []string{"backend", "service", "method"})
+
+	AuthCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_cache_requests_total",
+		Help:      "Token verification cache lookups, by result (hit or miss).",
+	}, []string{"result"})
+
+	AuthLockouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_lockouts_total",
+		Help:      "Emails and client IPs locked out after too many failed attempts, by flow and scope.",
+	}, []string{"flow", "scope"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by the rate limiter, by route group and client kind (user or ip).",
+	}, []string{"group", "client"})
+
+	ResponseCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "response_cache_requests_total",
+		Help:      "Response cache lookups, by cache namespace and result (hit, miss or bypass).",
+	}, []string{"namespace", "result"})
+
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_circuit_breaker_state",
+		Help:      "State of the circuit breaker of each backend: 0 closed, 1 open, 2 half-open.",
+	}, []string{"backend"})
+)
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// UnaryClientInterceptor records latency and status code of every RPC made
+// over a connection to backend.
+func UnaryClientInterceptor(backend string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		service, name := SplitMethod(method)
+		code := status.Code(err).String()
+		GrpcClientHandled.WithLabelValues(backend, service, name, code).Inc()
+		GrpcClientDuration.WithLabelValues(backend, service, name, code).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// SplitMethod splits a full gRPC method name such as
+// /genproto.PostService/Get into its service and method parts.
+func SplitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
@@ -0,0 +1,161 @@
+package policy
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Wildcard matches any resource or action.
+	Wildcard = "*"
+
+	// ConditionOwner restricts a rule to resources owned by the user.
+	ConditionOwner = "owner"
+)
+
+//go:embed default.yaml
+var defaultPolicy []byte
+
+// Rule grants a user type the listed actions on a resource, optionally
+// only under a condition.
+type Rule struct {
+	Resource  string   `yaml:"resource" json:"resource"`
+	Actions   []string `yaml:"actions" json:"actions"`
+	Condition string   `yaml:"condition,omitempty" json:"condition,omitempty"`
+}
+
+type document struct {
+	Roles map[string][]Rule `yaml:"roles"`
+}
+
+// Decision is the outcome of evaluating the policy for a request.
+type Decision struct {
+	// Known is false when the policy has no rules for the user type.
+	Known   bool
+	Allowed bool
+	// Condition must additionally hold for the request to be allowed.
+	Condition string
+}
+
+// Policy says which actions each user type may perform on each resource.
+// It is safe for concurrent use and can be reloaded while in use.
+type Policy struct {
+	path  string
+	roles atomic.Pointer[map[string][]Rule]
+}
+
+// Load reads the policy file at path, or the built-in policy when path is
+// empty. Both YAML and JSON files are accepted:
+//
+//	roles:
+//	  user:
+//	    - resource: posts
+//	      actions: [update, delete]
+//	      condition: owner
+func Load(path string) (*Policy, error) {
+	p := &Policy{path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload reads the policy file again. The policy in use is kept when the
+// file can't be read or is invalid.
+func (p *Policy) Reload() error {
+	data := defaultPolicy
+	if p.path != "" {
+		var err error
+		if data, err = os.ReadFile(p.path); err != nil {
+			return fmt.Errorf("failed to read policy: %v", err)
+		}
+	}
+
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse policy: %v", err)
+	}
+	if err := doc.validate(); err != nil {
+		return fmt.Errorf("invalid policy: %v", err)
+	}
+
+	p.roles.Store(&doc.Roles)
+	return nil
+}
+
+func (d *document) validate() error {
+	if len(d.Roles) == 0 {
+		return fmt.Errorf("no roles defined")
+	}
+
+	for role, rules := range d.Roles {
+		for i, rule := range rules {
+			if rule.Resource == "" || len(rule.Actions) == 0 {
+				return fmt.Errorf("rule %d of %s needs a resource and at least one action", i, role)
+			}
+			if rule.Condition != "" && rule.Condition != ConditionOwner {
+				return fmt.Errorf("rule %d of %s has unknown condition %q", i, role, rule.Condition)
+			}
+		}
+	}
+	return nil
+}
+
+// Decide evaluates the policy for role performing action on resource.
+// An unconditional rule wins over a conditional one when both match. A nil
+// Policy knows no roles.
+func (p *Policy) Decide(role, resource, action string) Decision {
+	if p == nil {
+		return Decision{}
+	}
+
+	rules, ok := (*p.roles.Load())[role]
+	if !ok {
+		return Decision{}
+	}
+
+	d := Decision{Known: true}
+	for _, rule := range rules {
+		if !rule.matches(resource, action) {
+			continue
+		}
+		if rule.Condition == "" {
+			return Decision{Known: true, Allowed: true}
+		}
+		d.Allowed, d.Condition = true, rule.Condition
+	}
+	return d
+}
+
+func (r Rule) matches(resource, action string) bool {
+	if r.Resource != Wildcard && r.Resource != resource {
+		return false
+	}
+	for _, a := range r.Actions {
+		if a == Wildcard || a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the rules in effect for role.
+func (p *Policy) Rules(role string) ([]Rule, bool) {
+	rules, ok := (*p.roles.Load())[role]
+	return rules, ok
+}
+
+// Roles returns the user types the policy has rules for, sorted.
+func (p *Policy) Roles() []string {
+	roles := make([]string, 0)
+	for role := range *p.roles.Load() {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch reloads the policy whenever its file changes, until ctx is done.
+// The directory is watched rather than the file itself so that editors and
+// config management tools replacing the file are noticed too. Watching the
+// built-in policy is a no-op.
+func (p *Policy) Watch(ctx context.Context) error {
+	if p.path == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch policy: %v", err)
+	}
+
+	file := filepath.Clean(p.path)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch policy: %v", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				if err := p.Reload(); err != nil {
+					log.Printf("keeping previous auth policy: %v", err)
+					continue
+				}
+				log.Printf("reloaded auth policy from %s", file)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("auth policy watcher: %v", err)
+			}
+		}
+	}()
+	return nil
+}
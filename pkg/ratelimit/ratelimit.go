@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/pkg/store"
+)
+
+// Limit is a token bucket holding up to Burst tokens that refills at Rate
+// tokens per second. Every request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerPeriod returns a Limit allowing n requests per period, all of which
+// may be spent at once.
+func PerPeriod(n int, period time.Duration) Limit {
+	if n <= 0 || period <= 0 {
+		return Limit{}
+	}
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: n}
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result is the state of a bucket after a request tried to take a token.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long it takes until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long a rejected request has to wait for a token.
+	RetryAfter time.Duration
+}
+
+// Limiter keeps the buckets. Implementations must be safe for concurrent
+// use.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// New returns a limiter keeping its buckets next to the rest of the shared
+// state, so every gateway instance using the same Redis sees the same
+// buckets.
+func New(s store.Store) Limiter {
+	if r, ok := s.(*store.Redis); ok {
+		return NewRedis(r.Client())
+	}
+	return NewMemory()
+}
+
+func result(limit Limit, allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
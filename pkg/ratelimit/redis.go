@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// allowScript refills and takes from a bucket stored as a hash in one
+// round trip. The time is taken from the server so the clocks of the
+// gateway instances don't matter. The bucket expires once it would be
+// full again.
+var allowScript = redis.NewScript(`
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) * 1000 / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis keeps the buckets in a Redis server shared by every gateway
+// instance.
+type Redis struct {
+	client *redis.Client
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := allowScript.Run(ctx, r.client, []string{key}, limit.Rate, limit.Burst).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	raw, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(limit, allowed == 1, tokens), nil
+}
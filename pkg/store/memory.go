@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Memory drops expired keys nobody asked for.
+const sweepInterval = time.Minute
+
+// Memory is a Store kept in the process. It is only suitable when a single
+// gateway instance is running, since instances don't see each other's keys.
+type Memory struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+type memoryItem struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && now.After(i.expiresAt)
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		items:     make(map[string]memoryItem),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok || item.expired(time.Now()) {
+		delete(m.items, key)
+		return "", ErrNotFound
+	}
+	return item.value, nil
+}
+
+func (m *Memory) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(key, value, ttl)
+	return nil
+}
+
+func (m *Memory) SetNX(_ context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if item, ok := m.items[key]; ok && !item.expired(time.Now()) {
+		return false, nil
+	}
+	m.set(key, value, ttl)
+	return true, nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.items, key)
+	return nil
+}
+
+func (m *Memory) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok || item.expired(time.Now()) {
+		m.set(key, "1", ttl)
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value of %q is not a counter", key)
+	}
+	item.value = strconv.FormatInt(n+1, 10)
+	m.items[key] = item
+	return n + 1, nil
+}
+
+func (m *Memory) set(key, value string, ttl time.Duration) {
+	now := time.Now()
+
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = now.Add(ttl)
+	}
+	m.items[key] = item
+
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for k, i := range m.items {
+			if i.expired(now) {
+				delete(m.items, k)
+			}
+		}
+		m.lastSweep = now
+	}
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samandar2605/medium_api_gateway/config"
+)
+
+// connectTimeout bounds the check that a remote store can be reached.
+const connectTimeout = 5 * time.Second
+
+// Values of config.Config.StoreBackend.
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// New creates the store selected by cfg.StoreBackend.
+func New(cfg config.Config) (Store, error) {
+	switch cfg.StoreBackend {
+	case BackendMemory, "":
+		return NewMemory(), nil
+	case BackendRedis:
+		r := NewRedis(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		defer cancel()
+		if err := r.Ping(ctx); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to reach redis at %s: %w", cfg.RedisAddr, err)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown store backend %q", cfg.StoreBackend)
+}
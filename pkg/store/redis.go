@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// incrScript increments a counter and sets its expiry only when the
+// increment created it, in one round trip.
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// Redis is a Store kept in a Redis server, so every gateway instance
+// pointed at it shares the same state.
+type Redis struct {
+	client *redis.Client
+}
+
+func NewRedis(addr, password string, db int) *Redis {
+	return &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (r *Redis) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *Redis) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, key).Err()
+}
+
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(ctx, r.client, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+// Client returns the underlying client, for features needing more than a
+// key/value store.
+func (r *Redis) Client() *redis.Client {
+	return r.client
+}
+
+// Ping checks that the server can be reached.
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+// Store is the key/value storage shared state of the gateway lives in,
+// such as revoked tokens. Every key expires after the TTL it was written
+// with; a TTL of zero means the key never expires.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX sets key only if it does not exist yet and reports whether it
+	// did, so that one caller out of many concurrent ones wins.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+	// Incr increments the counter at key and returns its new value. A
+	// counter created by the call expires after ttl; later increments
+	// don't extend it.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
@@ -0,0 +1,216 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// minSecretKeySize matches what user_service requires of the key it signs
+// tokens with.
+const minSecretKeySize = 32
+
+// Claims is the payload user_service puts into the access tokens it issues.
+type Claims struct {
+	ID        string
+	UserID    int64
+	Email     string
+	UserType  string
+	IssuedAt  time.Time
+	ExpiredAt time.Time
+}
+
+// ClaimLayout tells how Claims are laid out in a token: the name of every
+// claim, and whether times are seconds since the epoch (UnixTimes) or
+// RFC 3339 strings. When reading a token either form of time is accepted,
+// and a user id may also be a decimal string, as in "sub".
+type ClaimLayout struct {
+	ID        string
+	UserID    string
+	Email     string
+	UserType  string
+	IssuedAt  string
+	ExpiredAt string
+	UnixTimes bool
+}
+
+// DefaultClaimLayout is the layout of user_service's tokens.
+var DefaultClaimLayout = ClaimLayout{
+	ID:        "id",
+	UserID:    "user_id",
+	Email:     "email",
+	UserType:  "user_type",
+	IssuedAt:  "issued_at",
+	ExpiredAt: "expired_at",
+}
+
+// Maker signs and verifies HS256 access tokens with the secret key shared
+// with user_service, so tokens of either side are accepted by the other as
+// long as both agree on the layout.
+type Maker struct {
+	secretKey []byte
+	layout    ClaimLayout
+}
+
+func NewMaker(secretKey string, layout ClaimLayout) (*Maker, error) {
+	if len(secretKey) < minSecretKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+	}
+	names := []string{layout.ID, layout.UserID, layout.Email, layout.UserType, layout.IssuedAt, layout.ExpiredAt}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" || seen[name] {
+			return nil, fmt.Errorf("invalid claim layout: every claim needs a name of its own")
+		}
+		seen[name] = true
+	}
+	return &Maker{secretKey: []byte(secretKey), layout: layout}, nil
+}
+
+// Create issues an access token for the user valid for duration.
+func (m *Maker) Create(userID int64, email, userType string, duration time.Duration) (string, *Claims, error) {
+	id, err := NewID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		ID:        id,
+		UserID:    userID,
+		Email:     email,
+		UserType:  userType,
+		IssuedAt:  now,
+		ExpiredAt: now.Add(duration),
+	}
+
+	l := m.layout
+	payload := jwt.MapClaims{
+		l.ID:        claims.ID,
+		l.UserID:    claims.UserID,
+		l.Email:     claims.Email,
+		l.UserType:  claims.UserType,
+		l.IssuedAt:  m.formatTime(claims.IssuedAt),
+		l.ExpiredAt: m.formatTime(claims.ExpiredAt),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString(m.secretKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+func (m *Maker) formatTime(t time.Time) interface{} {
+	if m.layout.UnixTimes {
+		return t.Unix()
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (m *Maker) Verify(accessToken string) (*Claims, error) {
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return m.secretKey, nil
+	}
+
+	// The claims are checked below, where their names are known.
+	parser := jwt.NewParser(jwt.WithJSONNumber(), jwt.WithoutClaimsValidation())
+	payload := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(accessToken, payload, keyFunc); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	l := m.layout
+	var claims Claims
+	var ok bool
+	if claims.ID, ok = stringClaim(payload[l.ID]); !ok {
+		return nil, ErrInvalidToken
+	}
+	if claims.UserID, ok = intClaim(payload[l.UserID]); !ok {
+		return nil, ErrInvalidToken
+	}
+	if claims.Email, ok = stringClaim(payload[l.Email]); !ok {
+		return nil, ErrInvalidToken
+	}
+	if claims.UserType, ok = stringClaim(payload[l.UserType]); !ok {
+		return nil, ErrInvalidToken
+	}
+	if claims.IssuedAt, ok = timeClaim(payload[l.IssuedAt]); !ok {
+		return nil, ErrInvalidToken
+	}
+	if claims.ExpiredAt, ok = timeClaim(payload[l.ExpiredAt]); !ok {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.ExpiredAt.IsZero() || time.Now().After(claims.ExpiredAt) {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+// stringClaim reads an optional string claim.
+func stringClaim(v interface{}) (string, bool) {
+	if v == nil {
+		return "", true
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+func intClaim(v interface{}) (int64, bool) {
+	var n json.Number
+	switch v := v.(type) {
+	case json.Number:
+		n = v
+	case string:
+		n = json.Number(v)
+	default:
+		return 0, false
+	}
+	i, err := n.Int64()
+	return i, err == nil
+}
+
+// timeClaim reads a time given either as seconds since the epoch or as an
+// RFC 3339 string. A missing time is zero.
+func timeClaim(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case nil:
+		return time.Time{}, true
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// NewID returns a random UUID (version 4) to identify a token by.
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %v", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,232 @@
+package token_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/samandar2605/medium_api_gateway/pkg/token"
+)
+
+const testSecretKey = "0123456789abcdef0123456789abcdef"
+
+// userServicePayload is the payload user_service signs its access tokens
+// with, as laid out by its token package.
+type userServicePayload struct {
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	UserType  string    `json:"user_type"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+func (p *userServicePayload) Valid() error { return nil }
+
+// standardLayout keeps the claims under their registered JWT names.
+var standardLayout = token.ClaimLayout{
+	ID:        "jti",
+	UserID:    "sub",
+	Email:     "email",
+	UserType:  "role",
+	IssuedAt:  "iat",
+	ExpiredAt: "exp",
+	UnixTimes: true,
+}
+
+func newMaker(t *testing.T, layout token.ClaimLayout) *token.Maker {
+	t.Helper()
+
+	m, err := token.NewMaker(testSecretKey, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func sign(t *testing.T, claims jwt.Claims) string {
+	t.Helper()
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecretKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyUserServiceToken(t *testing.T) {
+	issuedAt := time.Now().Truncate(time.Second)
+	signed := sign(t, &userServicePayload{
+		ID:        "4b0c5ba8-5d42-4f6e-9a3b-1f2e3d4c5b6a",
+		UserID:    7,
+		Email:     "user@example.com",
+		UserType:  "admin",
+		IssuedAt:  issuedAt,
+		ExpiredAt: issuedAt.Add(time.Hour),
+	})
+
+	claims, err := newMaker(t, token.DefaultClaimLayout).Verify(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := token.Claims{
+		ID:        "4b0c5ba8-5d42-4f6e-9a3b-1f2e3d4c5b6a",
+		UserID:    7,
+		Email:     "user@example.com",
+		UserType:  "admin",
+		IssuedAt:  issuedAt,
+		ExpiredAt: issuedAt.Add(time.Hour),
+	}
+	if !claimsEqual(*claims, want) {
+		t.Errorf("claims = %+v, want %+v", *claims, want)
+	}
+}
+
+func TestVerifyStandardClaims(t *testing.T) {
+	issuedAt := time.Now().Truncate(time.Second)
+	signed := sign(t, jwt.MapClaims{
+		"jti":   "abc",
+		"sub":   "7",
+		"email": "user@example.com",
+		"role":  "user",
+		"iat":   issuedAt.Unix(),
+		"exp":   issuedAt.Add(time.Hour).Unix(),
+	})
+
+	claims, err := newMaker(t, standardLayout).Verify(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := token.Claims{
+		ID:        "abc",
+		UserID:    7,
+		Email:     "user@example.com",
+		UserType:  "user",
+		IssuedAt:  issuedAt,
+		ExpiredAt: issuedAt.Add(time.Hour),
+	}
+	if !claimsEqual(*claims, want) {
+		t.Errorf("claims = %+v, want %+v", *claims, want)
+	}
+}
+
+func TestCreateFollowsLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout token.ClaimLayout
+		// want is the type every claim is written as.
+		want map[string]string
+	}{
+		{"user_service", token.DefaultClaimLayout, map[string]string{
+			"id": "string", "user_id": "number", "email": "string",
+			"user_type": "string", "issued_at": "string", "expired_at": "string",
+		}},
+		{"standard", standardLayout, map[string]string{
+			"jti": "string", "sub": "number", "email": "string",
+			"role": "string", "iat": "number", "exp": "number",
+		}},
+	}
+	for _, tt := range tests {
+		m := newMaker(t, tt.layout)
+		signed, created, err := m.Create(7, "user@example.com", "user", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		payload := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(signed, payload); err != nil {
+			t.Fatal(err)
+		}
+		if len(payload) != len(tt.want) {
+			t.Errorf("%s: payload %v, want claims %v", tt.name, payload, tt.want)
+		}
+		for name, typ := range tt.want {
+			var ok bool
+			switch typ {
+			case "string":
+				_, ok = payload[name].(string)
+			case "number":
+				_, ok = payload[name].(float64)
+			}
+			if !ok {
+				t.Errorf("%s: claim %q = %#v, want a %s", tt.name, name, payload[name], typ)
+			}
+		}
+
+		verified, err := m.Verify(signed)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if tt.layout.UnixTimes {
+			created.IssuedAt = created.IssuedAt.Truncate(time.Second)
+			created.ExpiredAt = created.ExpiredAt.Truncate(time.Second)
+		}
+		if !claimsEqual(*verified, *created) {
+			t.Errorf("%s: verified %+v, want %+v", tt.name, *verified, *created)
+		}
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Now()
+	otherKey, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 7, "expired_at": now.Add(time.Hour).Format(time.RFC3339),
+	}).SignedString([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		"user_id": 7, "expired_at": now.Add(time.Hour).Format(time.RFC3339),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", sign(t, jwt.MapClaims{
+			"user_id": 7, "expired_at": now.Add(-time.Second).Format(time.RFC3339),
+		}), token.ErrExpiredToken},
+		{"no expiry", sign(t, jwt.MapClaims{"user_id": 7}), token.ErrExpiredToken},
+		{"other key", otherKey, token.ErrInvalidToken},
+		{"unsigned", unsigned, token.ErrInvalidToken},
+		{"no user id", sign(t, jwt.MapClaims{
+			"expired_at": now.Add(time.Hour).Format(time.RFC3339),
+		}), token.ErrInvalidToken},
+		{"malformed expiry", sign(t, jwt.MapClaims{
+			"user_id": 7, "expired_at": "tomorrow",
+		}), token.ErrInvalidToken},
+		{"other layout", sign(t, jwt.MapClaims{
+			"sub": "7", "exp": now.Add(time.Hour).Unix(),
+		}), token.ErrInvalidToken},
+		{"garbage", "not.a.token", token.ErrInvalidToken},
+	}
+	m := newMaker(t, token.DefaultClaimLayout)
+	for _, tt := range tests {
+		if _, err := m.Verify(tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNewMakerRejectsBadLayout(t *testing.T) {
+	missing := token.DefaultClaimLayout
+	missing.Email = ""
+	shared := token.DefaultClaimLayout
+	shared.IssuedAt = shared.ExpiredAt
+
+	for _, layout := range []token.ClaimLayout{missing, shared} {
+		if _, err := token.NewMaker(testSecretKey, layout); err == nil {
+			t.Errorf("layout %+v accepted", layout)
+		}
+	}
+}
+
+func claimsEqual(a, b token.Claims) bool {
+	return a.ID == b.ID && a.UserID == b.UserID && a.Email == b.Email && a.UserType == b.UserType &&
+		a.IssuedAt.Equal(b.IssuedAt) && a.ExpiredAt.Equal(b.ExpiredAt)
+}